@@ -1,6 +1,8 @@
 package app
 
 import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
 	"flag"
 	"os"
 	"path/filepath"
@@ -13,50 +15,87 @@ var (
 	c       string
 	v       bool
 	h       bool
-	upgrade bool
-	cp      bool
+	command string
 )
 
+// 子命令名称，别名不能与其相同，否则无法通过别名登录
+var commands = []string{"upgrade", "cp", "cluster", "mux", "status", "list", "export", "server", "group", "key"}
+
+// 是否为子命令
+func isCommand(arg string) bool {
+	for _, cmd := range commands {
+		if arg == cmd {
+			return true
+		}
+	}
+
+	return false
+}
+
 func init() {
 	// 取执行文件所在目录下的config.json
 	dir, _ := os.Executable()
 	c = filepath.Dir(dir) + "/config.json"
 
-	flag.StringVar(&c, "c", c, "指定配置文件路径")
-	flag.StringVar(&c, "config", c, "指定配置文件路径")
+	flag.StringVar(&c, "c", c, i18n.T("flag.config"))
+	flag.StringVar(&c, "config", c, i18n.T("flag.config"))
+
+	flag.BoolVar(&v, "v", v, i18n.T("flag.version"))
+	flag.BoolVar(&v, "version", v, i18n.T("flag.version"))
 
-	flag.BoolVar(&v, "v", v, "版本信息")
-	flag.BoolVar(&v, "version", v, "版本信息")
+	flag.BoolVar(&utils.Verbose, "vvv", utils.Verbose, i18n.T("flag.verbose"))
 
-	flag.BoolVar(&h, "h", h, "帮助信息")
-	flag.BoolVar(&h, "help", h, "帮助信息")
+	flag.BoolVar(&h, "h", h, i18n.T("flag.help"))
+	flag.BoolVar(&h, "help", h, i18n.T("flag.help"))
 
 	flag.Usage = usage
+}
+
+// 解析命令行参数
+func parseArgs() {
 	flag.Parse()
 
 	if len(flag.Args()) > 0 {
 		arg := flag.Arg(0)
-		switch arg {
-		case "upgrade":
-			upgrade = true
-		case "cp":
-			cp = true
-		default:
+		if isCommand(arg) {
+			command = arg
+		} else {
 			defaultServer = arg
 		}
 	}
 }
 
 func Run() {
+	parseArgs()
+
 	if v {
 		showVersion()
 	} else if h {
 		showHelp()
-	} else if upgrade {
-		showUpgrade()
-	} else if cp {
-		showCp(c)
 	} else {
-		showServers(c)
+		switch command {
+		case "upgrade":
+			showUpgrade()
+		case "cp":
+			showCp(c)
+		case "cluster":
+			showCluster(c)
+		case "mux":
+			showMux(c)
+		case "status":
+			showStatus(c)
+		case "list":
+			showList(c)
+		case "export":
+			showExport(c)
+		case "server":
+			showServerCmd(c)
+		case "group":
+			showGroupCmd(c)
+		case "key":
+			showKey(c)
+		default:
+			showServers(c)
+		}
 	}
 }
@@ -1,22 +1,29 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
 	ShowDetail bool                   `json:"show_detail"`
+	ShowStatus bool                   `json:"show_status"` // 菜单中显示服务器在线状态
+	Language   string                 `json:"language"`    // 界面语言 en/zh，为空时按环境变量选择
 	Servers    []*Server              `json:"servers"`
 	Groups     []*Group               `json:"groups"`
 	Options    map[string]interface{} `json:"options"`
+	Proxy      *Proxy                 `json:"proxy"`   // 全局默认代理
+	HostCA     []string               `json:"host_ca"` // 主机证书的签发机构，可为公钥或公钥文件路径
 
 	// 服务器map索引，可通过编号、别名快速定位到某一个服务器
 	serverIndex map[string]ServerIndex
@@ -24,17 +31,97 @@ type Config struct {
 }
 
 type Group struct {
-	GroupName string   `json:"group_name"`
-	Prefix    string   `json:"prefix"`
-	Servers   []Server `json:"servers"`
-	Collapse  bool     `json:"collapse"`
-	Proxy     *Proxy   `json:"proxy"`
+	GroupName    string                 `json:"group_name"`
+	Prefix       string                 `json:"prefix"`
+	Servers      []Server               `json:"servers"`
+	Groups       []*Group               `json:"groups,omitempty"` // 子组
+	Collapse     bool                   `json:"collapse"`
+	Options      map[string]interface{} `json:"options,omitempty"` // 组内服务器及子组继承的选项
+	Proxy        *Proxy                 `json:"proxy"`
+	ProxyCommand string                 `json:"proxy_command"`
+
+	parent *Group
+	path   string // 完整前缀，子组以 . 连接上级前缀，如 c.p
+}
+
+// 组内服务器编号的前缀，顶级组为前缀本身，子组为完整前缀加 .，如 c.p.1
+func (group *Group) indexPrefix() string {
+	if group.parent == nil {
+		return group.path
+	}
+
+	return group.path + "."
+}
+
+// 组名，子组包含上级组名，如 华东 / 生产
+func (group *Group) fullName() string {
+	if group.parent == nil {
+		return group.GroupName
+	}
+
+	return group.parent.fullName() + " / " + group.GroupName
+}
+
+// 组的层级，顶级组为0
+func (group *Group) depth() int {
+	if group.parent == nil {
+		return 0
+	}
+
+	return group.parent.depth() + 1
+}
+
+// 是否包含服务器（含子组）
+func (group *Group) hasServers() bool {
+	if len(group.Servers) > 0 {
+		return true
+	}
+
+	for _, child := range group.Groups {
+		if child.hasServers() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 是否为 ancestor 或其子组
+func (group *Group) isDescendantOf(ancestor *Group) bool {
+	for g := group; g != nil; g = g.parent {
+		if g == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 按深度优先顺序获取全部组
+func (cfg *Config) allGroups() []*Group {
+	groups := make([]*Group, 0)
+	var walk func(children []*Group)
+	walk = func(children []*Group) {
+		for _, group := range children {
+			groups = append(groups, group)
+			walk(group.Groups)
+		}
+	}
+	walk(cfg.Groups)
+
+	return groups
 }
 
 type ProxyType string
 
 const (
-	ProxyTypeSocks5 ProxyType = "SOCKS5"
+	ProxyTypeSocks5  ProxyType = "SOCKS5"
+	ProxyTypeSocks4  ProxyType = "SOCKS4"
+	ProxyTypeSocks4A ProxyType = "SOCKS4A"
+	ProxyTypeHttp    ProxyType = "HTTP"   // HTTP CONNECT
+	ProxyTypeHttps   ProxyType = "HTTPS"  // 通过TLS连接代理服务器的 HTTP CONNECT
+	ProxyTypeDirect  ProxyType = "DIRECT" // 直连，用于覆盖上级代理
+	ProxyTypeEnv     ProxyType = "ENV"    // 读取 ALL_PROXY、HTTPS_PROXY 环境变量，遵循 NO_PROXY
 )
 
 type Proxy struct {
@@ -53,9 +140,13 @@ const (
 )
 
 type ServerLog struct {
-	Enable   bool    `json:"enable"`
-	Filename string  `json:"filename"`
-	Mode     LogMode `json:"mode"`
+	Enable    bool    `json:"enable"`
+	Filename  string  `json:"filename"`
+	Mode      LogMode `json:"mode"`       // cover-覆盖，append-追加（默认）
+	Timestamp bool    `json:"timestamp"`  // 每行添加时间戳
+	StripAnsi bool    `json:"strip_ansi"` // 去除颜色等ANSI控制字符
+	MaxSize   string  `json:"max_size"`   // 按大小轮转，如 10M，为空不轮转
+	MaxAge    string  `json:"max_age"`    // 按时间轮转，如 24h，为空不轮转
 }
 
 const (
@@ -65,61 +156,264 @@ const (
 
 type IndexType int
 type ServerIndex struct {
+	index       string // 编号
 	indexType   IndexType
-	groupIndex  int
-	serverIndex int
+	group       *Group // 所在组，默认组为 nil
+	serverIndex int    // 在组内的位置
 	server      *Server
 }
 
+// 与子命令同名的别名，这些别名无法在命令行中使用
+func (cfg *Config) commandAliases() []string {
+	aliases := make([]string, 0)
+	servers := cfg.groupServers(nil)
+	for _, group := range cfg.allGroups() {
+		servers = append(servers, cfg.groupServers(group)...)
+	}
+
+	for _, server := range servers {
+		if isCommand(server.Alias) {
+			aliases = append(aliases, server.Alias)
+		}
+	}
+
+	return aliases
+}
+
 // 创建服务器索引
-func (cfg *Config) createServerIndex() {
+// 编号由组前缀及服务器的 id 组成，不随服务器在列表中的位置变化
+func (cfg *Config) createServerIndex() error {
 	cfg.serverIndex = make(map[string]ServerIndex)
-	for i := range cfg.Servers {
-		server := cfg.Servers[i]
-		server.Format()
-		index := strconv.Itoa(i + 1)
 
-		if _, ok := cfg.serverIndex[index]; ok {
-			continue
-		}
+	servers := cfg.Servers
+	if err := assignServerIds(servers, ""); err != nil {
+		return err
+	}
 
-		server.MergeOptions(cfg.Options, false)
-		cfg.serverIndex[index] = ServerIndex{
+	for i := range servers {
+		server := servers[i]
+		server.Format()
+		server.group = nil
+		server.groupName = ""
+
+		err := cfg.addServerIndex(ServerIndex{
+			index:       strconv.Itoa(server.Id),
 			indexType:   IndexTypeServer,
-			groupIndex:  -1,
 			serverIndex: i,
 			server:      server,
-		}
-		if server.Alias != "" {
-			cfg.serverIndex[server.Alias] = cfg.serverIndex[index]
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	for i := range cfg.Groups {
-		group := cfg.Groups[i]
-		for j := range group.Servers {
-			server := &group.Servers[j]
+	return cfg.createGroupIndex(cfg.Groups, nil)
+}
+
+// 创建组内服务器的索引，并递归处理子组
+func (cfg *Config) createGroupIndex(groups []*Group, parent *Group) error {
+	for _, group := range groups {
+		group.parent = parent
+		group.path = group.Prefix
+		if parent != nil {
+			group.path = parent.path + "." + group.Prefix
+		}
+
+		servers := cfg.groupServers(group)
+		if err := assignServerIds(servers, group.indexPrefix()); err != nil {
+			return err
+		}
+
+		for j, server := range servers {
 			server.Format()
-			server.groupName = group.GroupName
+			server.groupName = group.fullName()
 			server.group = group
-			index := group.Prefix + strconv.Itoa(j+1)
-
-			if _, ok := cfg.serverIndex[index]; ok {
-				continue
-			}
 
-			server.MergeOptions(cfg.Options, false)
-			cfg.serverIndex[index] = ServerIndex{
+			err := cfg.addServerIndex(ServerIndex{
+				index:       group.indexPrefix() + strconv.Itoa(server.Id),
 				indexType:   IndexTypeGroup,
-				groupIndex:  i,
+				group:       group,
 				serverIndex: j,
 				server:      server,
+			})
+			if err != nil {
+				return err
 			}
-			if server.Alias != "" {
-				cfg.serverIndex[server.Alias] = cfg.serverIndex[index]
+		}
+
+		if err := cfg.createGroupIndex(group.Groups, group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 添加索引，编号或别名重复时返回错误
+func (cfg *Config) addServerIndex(serverIndex ServerIndex) error {
+	server := serverIndex.server
+	if _, ok := cfg.serverIndex[serverIndex.index]; ok {
+		return errors.New(i18n.T("config.index_duplicate", serverIndex.index))
+	}
+
+	server.index = serverIndex.index
+	server.globalProxy = cfg.Proxy
+	server.hostCA = cfg.HostCA
+	cfg.mergeServerOptions(server)
+	cfg.serverIndex[serverIndex.index] = serverIndex
+
+	if server.Alias != "" {
+		if _, ok := cfg.serverIndex[server.Alias]; ok {
+			return errors.New(i18n.T("config.alias_duplicate", server.Alias))
+		}
+		cfg.serverIndex[server.Alias] = serverIndex
+	}
+
+	return nil
+}
+
+// 合并选项，优先级为 服务器 > 所在组 > 上级组 > 全局
+// 服务器自身的选项单独保存，保存配置时不写入继承的选项
+func (cfg *Config) mergeServerOptions(server *Server) {
+	if !server.optionsMerged {
+		server.ownOptions = server.Options
+		server.optionsMerged = true
+	}
+
+	server.Options = nil
+	server.MergeOptions(server.ownOptions, true)
+	for group := server.group; group != nil; group = group.parent {
+		server.MergeOptions(group.Options, false)
+	}
+	server.MergeOptions(cfg.Options, false)
+}
+
+// 为未设置 id 的服务器分配 id，优先使用所在位置，与旧版本的编号保持一致
+func assignServerIds(servers []*Server, prefix string) error {
+	used := make(map[int]bool)
+	for _, server := range servers {
+		if server.Id < 0 {
+			return errors.New(i18n.T("config.id_negative", server.Name))
+		}
+		if server.Id == 0 {
+			continue
+		}
+		if used[server.Id] {
+			return errors.New(i18n.T("config.id_duplicate", prefix+strconv.Itoa(server.Id)))
+		}
+		used[server.Id] = true
+	}
+
+	for i, server := range servers {
+		if server.Id != 0 {
+			continue
+		}
+
+		id := i + 1
+		for used[id] {
+			id++
+		}
+		server.Id = id
+		used[id] = true
+	}
+
+	return nil
+}
+
+// 获取下一个可用的 id
+func nextServerId(servers []*Server) int {
+	id := 0
+	for _, server := range servers {
+		if server.Id > id {
+			id = server.Id
+		}
+	}
+
+	return id + 1
+}
+
+// 按编号、别名或IP查找服务器，多台服务器使用同一IP时返回错误
+func (cfg *Config) lookupServer(name string) (*Server, error) {
+	if serverIndex, ok := cfg.serverIndex[name]; ok {
+		return serverIndex.server, nil
+	}
+
+	var found *Server
+	for _, serverIndex := range cfg.serverIndex {
+		if serverIndex.server.Ip != name || serverIndex.server == found {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New(i18n.T("server.ip_ambiguous", name))
+		}
+		found = serverIndex.server
+	}
+
+	if found == nil {
+		return nil, errors.New(i18n.T("server.not_found", name))
+	}
+
+	return found, nil
+}
+
+// 解析目标服务器
+// 支持编号、别名、组前缀（组及子组内全部服务器，子组使用完整前缀如 c.p）及 all，多个目标可用逗号分隔，返回去重后的编号
+func (cfg *Config) resolveTargets(targets []string) ([]string, error) {
+	indexes := make([]string, 0)
+	exists := make(map[string]bool)
+	appendIndex := func(index string) {
+		if serverIndex, ok := cfg.serverIndex[index]; ok && !exists[serverIndex.index] {
+			exists[serverIndex.index] = true
+			indexes = append(indexes, serverIndex.index)
+		}
+	}
+	var appendGroup func(group *Group)
+	appendGroup = func(group *Group) {
+		for j := range group.Servers {
+			appendIndex(group.Servers[j].index)
+		}
+		for _, child := range group.Groups {
+			appendGroup(child)
+		}
+	}
+
+	for _, arg := range targets {
+		for _, target := range strings.Split(arg, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+
+			if _, ok := cfg.serverIndex[target]; ok {
+				appendIndex(target)
+				continue
+			}
+
+			if target == "all" {
+				for _, server := range cfg.Servers {
+					appendIndex(server.index)
+				}
+				for _, group := range cfg.Groups {
+					appendGroup(group)
+				}
+				continue
+			}
+
+			matched := false
+			for _, group := range cfg.allGroups() {
+				if target == group.path {
+					matched = true
+					appendGroup(group)
+				}
+			}
+
+			if !matched {
+				return nil, errors.New(i18n.T("server.not_found", target))
 			}
 		}
 	}
+
+	return indexes, nil
 }
 
 // 保存配置文件
@@ -171,6 +465,6 @@ func (cfg *Config) backup() error {
 		return err
 	}
 
-	utils.Infoln("配置文件已备份：", backupFile)
+	utils.Infoln(i18n.T("config.backup", backupFile))
 	return nil
 }
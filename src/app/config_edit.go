@@ -0,0 +1,516 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 组前缀不能包含 . 且不能以数字结尾，否则编号会产生歧义，如前缀 a1 的 1 号与前缀 a 的 11 号
+var prefixRegexp = regexp.MustCompile(`^[^\s,.]*[^\s,.0-9]$`)
+
+// 查找组，支持完整前缀（子组如 c.p）或组名
+func (cfg *Config) findGroup(key string) *Group {
+	groups := cfg.allGroups()
+	for _, group := range groups {
+		if group.path == key {
+			return group
+		}
+	}
+
+	for _, group := range groups {
+		if group.GroupName == key {
+			return group
+		}
+	}
+
+	return nil
+}
+
+// 获取组所在的列表，顶级组为 cfg.Groups，子组为上级组的 Groups
+func (cfg *Config) siblings(group *Group) *[]*Group {
+	if group.parent == nil {
+		return &cfg.Groups
+	}
+
+	return &group.parent.Groups
+}
+
+// 组在所在列表中的位置
+func groupPosition(groups []*Group, group *Group) int {
+	for i, g := range groups {
+		if g == group {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// 配置快照，修改后重建索引失败时用于恢复
+type configSnapshot struct {
+	servers     []*Server
+	serverItems []Server
+	groups      []*Group
+	groupItems  map[*Group]Group
+}
+
+// 保存当前服务器及组的状态
+func (cfg *Config) snapshot() configSnapshot {
+	snapshot := configSnapshot{
+		servers:    append([]*Server{}, cfg.Servers...),
+		groups:     append([]*Group{}, cfg.Groups...),
+		groupItems: make(map[*Group]Group),
+	}
+	for _, server := range cfg.Servers {
+		snapshot.serverItems = append(snapshot.serverItems, *server)
+	}
+	snapshot.saveGroups(cfg.Groups)
+
+	return snapshot
+}
+
+func (snapshot configSnapshot) saveGroups(groups []*Group) {
+	for _, group := range groups {
+		item := *group
+		item.Servers = append([]Server{}, group.Servers...)
+		item.Groups = append([]*Group{}, group.Groups...)
+		snapshot.groupItems[group] = item
+		snapshot.saveGroups(group.Groups)
+	}
+}
+
+// 重建索引，失败时恢复到 snapshot 的状态
+func (cfg *Config) rebuildIndex(snapshot configSnapshot) error {
+	err := cfg.createServerIndex()
+	if err == nil {
+		return nil
+	}
+
+	cfg.Servers = snapshot.servers
+	for i, server := range snapshot.servers {
+		*server = snapshot.serverItems[i]
+	}
+	cfg.Groups = snapshot.groups
+	for group, item := range snapshot.groupItems {
+		*group = item
+	}
+	_ = cfg.createServerIndex()
+
+	return err
+}
+
+// 查找服务器，支持编号及别名
+func (cfg *Config) findServer(id string) (ServerIndex, error) {
+	serverIndex, ok := cfg.serverIndex[id]
+	if !ok {
+		return serverIndex, errors.New(i18n.T("server.not_found", id))
+	}
+
+	return serverIndex, nil
+}
+
+// 获取组内服务器，group 为 nil 时为默认组
+func (cfg *Config) groupServers(group *Group) []*Server {
+	if group == nil {
+		return cfg.Servers
+	}
+
+	servers := make([]*Server, len(group.Servers))
+	for i := range group.Servers {
+		servers[i] = &group.Servers[i]
+	}
+
+	return servers
+}
+
+// 移动到其他组时，若 id 已被目标组使用则重新分配
+func (cfg *Config) freeServerId(server *Server, group *Group) {
+	servers := cfg.groupServers(group)
+	for _, s := range servers {
+		if s.Id == server.Id {
+			server.Id = nextServerId(servers)
+			return
+		}
+	}
+}
+
+// 校验服务器配置，self 为被编辑的服务器，新增时为 nil
+func (cfg *Config) validateServer(server *Server, self *Server) error {
+	if strings.TrimSpace(server.Name) == "" {
+		return errors.New(i18n.T("validate.name_empty"))
+	}
+
+	if strings.TrimSpace(server.Ip) == "" {
+		return errors.New(i18n.T("validate.ip_empty"))
+	}
+
+	if server.Port < 1 || server.Port > 65535 {
+		return errors.New(i18n.T("validate.port_range"))
+	}
+
+	switch strings.ToLower(server.Method) {
+	case "password":
+	case "key":
+		// 未指定密钥时使用 ~/.ssh/id_rsa，指定时检查文件是否存在
+		if server.Key != "" {
+			if _, err := utils.FileIsExists(server.Key); err != nil {
+				return errors.New(i18n.T("validate.key_missing", server.Key))
+			}
+		}
+	default:
+		return errors.New(i18n.T("validate.method"))
+	}
+
+	if server.PasswordSource != "" {
+		if _, _, err := parseSecretSource(server.PasswordSource); err != nil {
+			return err
+		}
+	}
+
+	if server.Alias != "" && (self == nil || server.Alias != self.Alias) {
+		if err := cfg.validateAlias(server.Alias, self); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 别名不能与其他服务器的编号、别名及组前缀冲突
+func (cfg *Config) validateAlias(alias string, self *Server) error {
+	if alias == "all" || strings.ContainsAny(alias, " \t,") {
+		return errors.New(i18n.T("alias.invalid", alias))
+	}
+
+	if isCommand(alias) {
+		return errors.New(i18n.T("alias.command_conflict", alias))
+	}
+
+	if serverIndex, ok := cfg.serverIndex[alias]; ok && serverIndex.server != self {
+		return errors.New(i18n.T("alias.used", alias))
+	}
+
+	if isIndexLike(alias, "") {
+		return errors.New(i18n.T("alias.index_conflict", alias))
+	}
+
+	for _, group := range cfg.allGroups() {
+		if alias == group.path || isIndexLike(alias, group.indexPrefix()) {
+			return errors.New(i18n.T("alias.group_conflict", alias, group.path))
+		}
+	}
+
+	return nil
+}
+
+// 是否为 前缀+数字 形式的编号
+func isIndexLike(str string, prefix string) bool {
+	if !strings.HasPrefix(str, prefix) || len(str) == len(prefix) {
+		return false
+	}
+
+	for _, c := range str[len(prefix):] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 校验组前缀，前缀在同一上级组内唯一，self 为被编辑的组，新增时为 nil
+func (cfg *Config) validatePrefix(prefix string, self *Group, parent *Group) error {
+	if !prefixRegexp.MatchString(prefix) || prefix == "all" {
+		return errors.New(i18n.T("prefix.invalid", prefix))
+	}
+
+	groups := cfg.Groups
+	path := prefix
+	if parent != nil {
+		groups = parent.Groups
+		path = parent.path + "." + prefix
+	}
+
+	for _, group := range groups {
+		if group != self && group.Prefix == prefix {
+			return errors.New(i18n.T("prefix.used", path))
+		}
+	}
+
+	indexPrefix := path
+	if parent != nil {
+		indexPrefix = path + "."
+	}
+	for _, serverIndex := range cfg.serverIndex {
+		if alias := serverIndex.server.Alias; alias == path || isIndexLike(alias, indexPrefix) {
+			return errors.New(i18n.T("prefix.alias_conflict", path))
+		}
+	}
+
+	return nil
+}
+
+// 添加服务器，groupKey 为空时添加到默认组，返回新服务器的编号
+func (cfg *Config) addServer(server Server, groupKey string) (string, error) {
+	server.Format()
+	if err := cfg.validateServer(&server, nil); err != nil {
+		return "", err
+	}
+
+	var group *Group
+	if groupKey != "" {
+		if group = cfg.findGroup(groupKey); group == nil {
+			return "", errors.New(i18n.T("group.not_found", groupKey))
+		}
+	}
+
+	snapshot := cfg.snapshot()
+	server.Id = nextServerId(cfg.groupServers(group))
+	prefix := ""
+	if group != nil {
+		prefix = group.indexPrefix()
+		group.Servers = append(group.Servers, server)
+	} else {
+		cfg.Servers = append(cfg.Servers, &server)
+	}
+
+	if err := cfg.rebuildIndex(snapshot); err != nil {
+		return "", err
+	}
+
+	return prefix + strconv.Itoa(server.Id), nil
+}
+
+// 编辑服务器，修改内容通过 edit 设置，校验失败时不做修改
+func (cfg *Config) updateServer(id string, edit func(server *Server)) error {
+	serverIndex, err := cfg.findServer(id)
+	if err != nil {
+		return err
+	}
+
+	server := *serverIndex.server
+	edit(&server)
+	server.Format()
+	if err := cfg.validateServer(&server, serverIndex.server); err != nil {
+		return err
+	}
+
+	snapshot := cfg.snapshot()
+	*serverIndex.server = server
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 删除服务器
+func (cfg *Config) removeServer(id string) error {
+	serverIndex, err := cfg.findServer(id)
+	if err != nil {
+		return err
+	}
+
+	snapshot := cfg.snapshot()
+	i := serverIndex.serverIndex
+	if group := serverIndex.group; group == nil {
+		cfg.Servers = append(cfg.Servers[:i], cfg.Servers[i+1:]...)
+	} else {
+		group.Servers = append(group.Servers[:i], group.Servers[i+1:]...)
+	}
+
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 添加组，parentKey 不为空时添加为该组的子组
+func (cfg *Config) addGroup(name string, prefix string, parentKey string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New(i18n.T("group.name_empty"))
+	}
+
+	var parent *Group
+	if parentKey != "" {
+		if parent = cfg.findGroup(parentKey); parent == nil {
+			return errors.New(i18n.T("group.not_found", parentKey))
+		}
+	}
+
+	if err := cfg.validatePrefix(prefix, nil, parent); err != nil {
+		return err
+	}
+
+	snapshot := cfg.snapshot()
+	group := &Group{GroupName: name, Prefix: prefix}
+	if parent == nil {
+		cfg.Groups = append(cfg.Groups, group)
+	} else {
+		parent.Groups = append(parent.Groups, group)
+	}
+
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 修改组名及前缀，为空时不修改
+func (cfg *Config) renameGroup(key string, name string, prefix string) error {
+	group := cfg.findGroup(key)
+	if group == nil {
+		return errors.New(i18n.T("group.not_found", key))
+	}
+
+	if prefix != "" && prefix != group.Prefix {
+		if err := cfg.validatePrefix(prefix, group, group.parent); err != nil {
+			return err
+		}
+	}
+
+	snapshot := cfg.snapshot()
+	if prefix != "" {
+		group.Prefix = prefix
+	}
+	if name != "" {
+		group.GroupName = name
+	}
+
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 删除组时组内服务器的处理方式
+type GroupServersAction int
+
+const (
+	GroupServersRefuse  GroupServersAction = iota // 组内有服务器或子组时不删除
+	GroupServersDelete                            // 同时删除组内服务器及子组
+	GroupServersUngroup                           // 移动到默认组
+	GroupServersMove                              // 移动到其他组
+)
+
+// 删除组，action 为 GroupServersMove 时 target 为目标组
+// 移动服务器时，子组保留并移至上一级
+func (cfg *Config) removeGroup(key string, action GroupServersAction, target string) error {
+	group := cfg.findGroup(key)
+	if group == nil {
+		return errors.New(i18n.T("group.not_found", key))
+	}
+
+	var targetGroup *Group
+	switch action {
+	case GroupServersRefuse:
+		if len(group.Servers) > 0 || len(group.Groups) > 0 {
+			return errors.New(i18n.T("group.not_empty", key))
+		}
+	case GroupServersMove:
+		if targetGroup = cfg.findGroup(target); targetGroup == nil {
+			return errors.New(i18n.T("group.not_found", target))
+		}
+		if targetGroup == group {
+			return errors.New(i18n.T("group.move_to_self"))
+		}
+	}
+
+	snapshot := cfg.snapshot()
+	siblings := cfg.siblings(group)
+	i := groupPosition(*siblings, group)
+	children := make([]*Group, 0)
+	if action == GroupServersUngroup || action == GroupServersMove {
+		// 子组移至上一级，前缀不能与上一级的组冲突
+		for _, child := range group.Groups {
+			for _, sibling := range *siblings {
+				if sibling != group && sibling.Prefix == child.Prefix {
+					return errors.New(i18n.T("group.child_conflict", child.path))
+				}
+			}
+		}
+		children = group.Groups
+
+		for _, server := range group.Servers {
+			server := server
+			cfg.freeServerId(&server, targetGroup)
+			if targetGroup == nil {
+				cfg.Servers = append(cfg.Servers, &server)
+			} else {
+				targetGroup.Servers = append(targetGroup.Servers, server)
+			}
+		}
+	}
+
+	groups := append((*siblings)[:i:i], children...)
+	*siblings = append(groups, (*siblings)[i+1:]...)
+
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 调整组在同级中的顺序，position 从1开始
+func (cfg *Config) moveGroup(key string, position int) error {
+	group := cfg.findGroup(key)
+	if group == nil {
+		return errors.New(i18n.T("group.not_found", key))
+	}
+
+	siblings := cfg.siblings(group)
+	if position < 1 || position > len(*siblings) {
+		return errors.New(i18n.T("input.position_range", len(*siblings)))
+	}
+
+	snapshot := cfg.snapshot()
+	i := groupPosition(*siblings, group)
+	groups := append((*siblings)[:i:i], (*siblings)[i+1:]...)
+	*siblings = append(groups[:position-1], append([]*Group{group}, groups[position-1:]...)...)
+
+	return cfg.rebuildIndex(snapshot)
+}
+
+// 移动服务器到指定组的指定位置，target 为 nil 时移动到默认组，position 为0时移动到末尾
+// 组内调整位置不改变编号，移动到其他组时 id 已被占用才重新分配，返回服务器的新编号
+func (cfg *Config) moveServer(id string, target *Group, position int) (string, error) {
+	serverIndex, err := cfg.findServer(id)
+	if err != nil {
+		return "", err
+	}
+
+	source := serverIndex.group
+	length := len(cfg.groupServers(target))
+	if source == target {
+		length--
+	}
+
+	if position == 0 {
+		position = length + 1
+	}
+	if position < 1 || position > length+1 {
+		return "", errors.New(i18n.T("input.position_range", length+1))
+	}
+
+	snapshot := cfg.snapshot()
+	server := *serverIndex.server
+	server.group = nil
+	server.groupName = ""
+	if source != target {
+		cfg.freeServerId(&server, target)
+	}
+
+	// 先从原位置移除
+	i := serverIndex.serverIndex
+	if source == nil {
+		cfg.Servers = append(cfg.Servers[:i:i], cfg.Servers[i+1:]...)
+	} else {
+		source.Servers = append(source.Servers[:i:i], source.Servers[i+1:]...)
+	}
+
+	prefix := ""
+	if target == nil {
+		servers := append([]*Server{}, cfg.Servers[:position-1]...)
+		servers = append(servers, &server)
+		cfg.Servers = append(servers, cfg.Servers[position-1:]...)
+	} else {
+		prefix = target.indexPrefix()
+		servers := append([]Server{}, target.Servers[:position-1]...)
+		servers = append(servers, server)
+		target.Servers = append(servers, target.Servers[position-1:]...)
+	}
+
+	if err := cfg.rebuildIndex(snapshot); err != nil {
+		return "", err
+	}
+
+	return prefix + strconv.Itoa(server.Id), nil
+}
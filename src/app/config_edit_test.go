@@ -0,0 +1,373 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 创建测试用配置文件
+func loadTestConfig(t *testing.T, raw string) (*Config, func()) {
+	dir, err := ioutil.TempDir("", "autossh-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(file, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"servers": [{"name": "web", "ip": "10.0.0.1", "user": "root", "alias": "web"}],
+		"groups": [{"group_name": "db", "prefix": "d", "servers": [{"name": "mysql", "ip": "10.0.0.2", "user": "root"}]}]
+	}`)
+	defer clean()
+
+	index, err := cfg.addServer(Server{Name: "redis", Ip: "10.0.0.3", User: "root"}, "db")
+	if err != nil || index != "d2" {
+		t.Fatalf("addServer = %q, %v", index, err)
+	}
+
+	invalid := []Server{
+		{Ip: "10.0.0.4"},
+		{Name: "a", Ip: "10.0.0.4", Port: 70000},
+		{Name: "a", Ip: "10.0.0.4", Method: "key", Key: "/nonexistent/id_rsa"},
+		{Name: "a", Ip: "10.0.0.4", Alias: "web"},
+		{Name: "a", Ip: "10.0.0.4", Alias: "d3"},
+		{Name: "a", Ip: "10.0.0.4", Alias: "12"},
+		{Name: "a", Ip: "10.0.0.4", Alias: "list"},
+	}
+	for _, server := range invalid {
+		if _, err := cfg.addServer(server, ""); err == nil {
+			t.Errorf("addServer(%+v) expected error", server)
+		}
+	}
+	if _, err := cfg.addServer(Server{Name: "a", Ip: "10.0.0.4"}, "x"); err == nil {
+		t.Error("expected error for unknown group")
+	}
+
+	if err := cfg.updateServer("d2", func(server *Server) { server.Port = 2222 }); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.updateServer("web", func(server *Server) { server.Port = 0; server.Ip = "" }); err == nil {
+		t.Error("expected error for empty ip")
+	}
+	if cfg.Servers[0].Ip != "10.0.0.1" {
+		t.Error("invalid edit modified server")
+	}
+
+	// 未指定 key 时使用默认密钥
+	index, err = cfg.addServer(Server{Name: "key", Ip: "10.0.0.5", Method: "key"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.updateServer(index, func(server *Server) { server.User = "admin" }); err != nil {
+		t.Errorf("edit key server without key: %v", err)
+	}
+	if err := cfg.removeServer(index); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{"d", "c1", "", "all", "a b", "web"} {
+		if err := cfg.addGroup("group", prefix, ""); err == nil {
+			t.Errorf("addGroup(%q) expected error", prefix)
+		}
+	}
+	if err := cfg.addGroup("cache", "c", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.renameGroup("c", "redis", "r"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.removeGroup("d", GroupServersRefuse, ""); err == nil {
+		t.Error("expected error for non-empty group")
+	}
+	if err := cfg.removeGroup("r", GroupServersRefuse, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.removeServer("d1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.saveConfig(false); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(cfg.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Groups) != 1 || len(saved.Groups[0].Servers) != 1 || saved.Groups[0].Servers[0].Port != 2222 {
+		t.Errorf("saved config: %s", b)
+	}
+}
+
+func TestGroupManage(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"servers": [{"name": "s1", "ip": "10.0.0.1"}, {"name": "s2", "ip": "10.0.0.2", "alias": "s2"}],
+		"groups": [
+			{"group_name": "a", "prefix": "a", "servers": [{"name": "a1", "ip": "10.0.1.1"}, {"name": "a2", "ip": "10.0.1.2"}]},
+			{"group_name": "b", "prefix": "b", "servers": [{"name": "b1", "ip": "10.0.2.1"}]}
+		]
+	}`)
+	defer clean()
+
+	names := func(index ...string) []string {
+		result := make([]string, 0)
+		for _, i := range index {
+			if serverIndex, ok := cfg.serverIndex[i]; ok {
+				result = append(result, serverIndex.server.Name)
+			} else {
+				result = append(result, "")
+			}
+		}
+		return result
+	}
+	assert := func(index []string, want ...string) {
+		t.Helper()
+		got := names(index...)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%v = %v, want %v", index, got, want)
+			}
+		}
+	}
+
+	// 组内排序，编号不变
+	index, err := cfg.moveServer("a2", cfg.Groups[0], 1)
+	if err != nil || index != "a2" {
+		t.Fatalf("moveServer = %q, %v", index, err)
+	}
+	if cfg.Groups[0].Servers[0].Name != "a2" {
+		t.Error("server not reordered")
+	}
+	assert([]string{"a1", "a2"}, "a1", "a2")
+
+	// 移动到其他组，id 未被占用时保持不变
+	index, err = cfg.moveServer("s2", cfg.Groups[1], 0)
+	if err != nil || index != "b2" {
+		t.Fatalf("moveServer = %q, %v", index, err)
+	}
+	assert([]string{"1", "2", "b1", "b2", "s2"}, "s1", "", "b1", "s2", "s2")
+	if cfg.serverIndex["s2"].server.group != cfg.Groups[1] {
+		t.Error("server group not updated")
+	}
+
+	// 移动到默认组，id 已被占用时重新分配
+	index, err = cfg.moveServer("a1", nil, 1)
+	if err != nil || index != "2" {
+		t.Fatalf("moveServer = %q, %v", index, err)
+	}
+	assert([]string{"1", "2", "a1", "a2"}, "s1", "a1", "", "a2")
+	if cfg.Servers[0].Name != "a1" || cfg.serverIndex["2"].server.group != nil {
+		t.Error("ungrouped server not moved")
+	}
+
+	if _, err := cfg.moveServer("1", nil, 5); err == nil {
+		t.Error("expected error for invalid position")
+	}
+	assert([]string{"1", "2"}, "s1", "a1")
+
+	if err := cfg.moveGroup("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Groups[0].Prefix != "b" {
+		t.Error("group not moved")
+	}
+
+	if err := cfg.removeGroup("a", GroupServersMove, "b"); err != nil {
+		t.Fatal(err)
+	}
+	assert([]string{"b1", "b2", "b3"}, "b1", "s2", "a2")
+
+	if err := cfg.removeGroup("b", GroupServersUngroup, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Groups) != 0 || len(cfg.Servers) != 5 {
+		t.Errorf("groups = %d, servers = %d", len(cfg.Groups), len(cfg.Servers))
+	}
+	assert([]string{"1", "2", "3", "4", "5"}, "s1", "a1", "b1", "s2", "a2")
+}
+
+func TestCreateServerIndex(t *testing.T) {
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"servers": [{"name": "a", "id": 2}, {"name": "b"}, {"name": "c"}]}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+	for index, name := range map[string]string{"2": "a", "3": "b", "4": "c"} {
+		if serverIndex, ok := cfg.serverIndex[index]; !ok || serverIndex.server.Name != name {
+			t.Errorf("%s: want %s", index, name)
+		}
+	}
+
+	// 删除后其他服务器编号不变
+	if err := cfg.removeServer("3"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.serverIndex["4"].server.Name != "c" {
+		t.Error("index changed after remove")
+	}
+
+	invalid := []string{
+		`{"servers": [{"name": "a", "id": 1}, {"name": "b", "id": 1}]}`,
+		`{"servers": [{"name": "a", "alias": "x"}, {"name": "b", "alias": "x"}]}`,
+		`{"servers": [{"name": "a", "alias": "b1"}], "groups": [{"prefix": "b", "servers": [{"name": "b"}]}]}`,
+		`{"servers": [{"name": "a"}], "groups": [{"prefix": "", "servers": [{"name": "b"}]}]}`,
+	}
+	for _, raw := range invalid {
+		var cfg Config
+		if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.createServerIndex(); err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
+	}
+}
+
+func TestNestedGroups(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"options": {"ForwardAgent": false, "ServerAliveInterval": 30},
+		"groups": [{"group_name": "cn", "prefix": "c", "options": {"ForwardAgent": true},
+			"proxy": {"type": "SOCKS5", "server": "bastion", "port": 1080},
+			"servers": [{"name": "gw", "ip": "10.0.0.1"}],
+			"groups": [{"group_name": "prod", "prefix": "p", "options": {"ServerAliveInterval": 10},
+				"servers": [{"name": "web", "ip": "10.0.1.1", "options": {"TERM": "xterm"}}]}]
+		}]
+	}`)
+	defer clean()
+
+	serverIndex, ok := cfg.serverIndex["c.p.1"]
+	if !ok || serverIndex.server.Name != "web" {
+		t.Fatalf("c.p.1 not found")
+	}
+	server := serverIndex.server
+	if server.groupName != "cn / prod" {
+		t.Errorf("groupName = %q", server.groupName)
+	}
+
+	// 选项及代理逐级继承
+	want := map[string]interface{}{"ForwardAgent": true, "ServerAliveInterval": float64(10), "TERM": "xterm"}
+	for k, v := range want {
+		if server.Options[k] != v {
+			t.Errorf("option %s = %v, want %v", k, server.Options[k], v)
+		}
+	}
+	if _, p, err := server.effectiveProxy(); err != nil || p == nil || p.Server != "bastion" {
+		t.Errorf("effectiveProxy = %+v, %v", p, err)
+	}
+
+	// 组前缀包含子组
+	indexes, err := cfg.resolveTargets([]string{"c"})
+	if err != nil || len(indexes) != 2 || indexes[0] != "c1" || indexes[1] != "c.p.1" {
+		t.Errorf("resolveTargets(c) = %v, %v", indexes, err)
+	}
+	if indexes, _ := cfg.resolveTargets([]string{"c.p"}); len(indexes) != 1 {
+		t.Errorf("resolveTargets(c.p) = %v", indexes)
+	}
+
+	if err := cfg.addGroup("stage", "s", "c.p"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.addGroup("prod", "p", "c"); err == nil {
+		t.Error("expected error for duplicate prefix")
+	}
+	index, err := cfg.addServer(Server{Name: "api", Ip: "10.0.2.1"}, "c.p.s")
+	if err != nil || index != "c.p.s.1" {
+		t.Fatalf("addServer = %q, %v", index, err)
+	}
+
+	// 保存时只写入服务器自身的选项
+	b, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Server
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Options) != 1 || saved.Options["TERM"] != "xterm" {
+		t.Errorf("saved options = %v", saved.Options)
+	}
+
+	// 删除组时子组移至上一级
+	if err := cfg.removeGroup("c.p", GroupServersMove, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.serverIndex["c.s.1"]; !ok {
+		t.Error("subgroup not moved to parent")
+	}
+	if serverIndex, ok := cfg.serverIndex["c2"]; !ok || serverIndex.server.Name != "web" {
+		t.Error("server not moved to target group")
+	}
+}
+
+func TestConfigEditRollback(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"servers": [{"name": "web", "ip": "10.0.0.1", "alias": "x1"}, {"name": "api", "ip": "10.0.0.2", "alias": "y.p.1"}],
+		"groups": [
+			{"group_name": "a", "prefix": "a", "servers": [{"name": "db", "ip": "10.0.0.3"}]},
+			{"group_name": "b", "prefix": "b", "groups": [{"group_name": "p", "prefix": "p", "servers": [{"name": "mq", "ip": "10.0.0.4"}]}]}
+		]
+	}`)
+	defer clean()
+
+	if err := cfg.renameGroup("a", "", "x"); err == nil {
+		t.Error("expected error for prefix conflicting with alias x1")
+	}
+
+	// 子组的编号 y.p.1 与别名冲突，重建索引失败后应恢复
+	if err := cfg.renameGroup("b", "renamed", "y"); err == nil {
+		t.Fatal("expected error for child index conflicting with alias")
+	}
+	group := cfg.findGroup("b")
+	if group == nil || group.GroupName != "b" || cfg.findGroup("b.p") == nil {
+		t.Fatalf("group not restored: %+v", group)
+	}
+	for _, id := range []string{"1", "2", "a1", "b.p.1", "x1", "y.p.1"} {
+		if _, err := cfg.findServer(id); err != nil {
+			t.Errorf("findServer(%q): %v", id, err)
+		}
+	}
+
+	if err := cfg.renameGroup("a", "", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.findServer("c1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"servers": [{"name": "web", "ip": "10.0.0.1", "alias": "status"}],
+		"groups": [{"group_name": "db", "prefix": "d", "servers": [{"name": "mysql", "ip": "10.0.0.2", "alias": "mysql"}, {"name": "pg", "ip": "10.0.0.3", "alias": "export"}]}]
+	}`)
+	defer clean()
+
+	if aliases := cfg.commandAliases(); strings.Join(aliases, ",") != "status,export" {
+		t.Errorf("commandAliases = %v", aliases)
+	}
+}
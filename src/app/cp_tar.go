@@ -0,0 +1,687 @@
+package app
+
+import (
+	"archive/tar"
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	CompressNone = ""
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// 检查压缩方式
+func parseCompress(compress string) (string, error) {
+	switch compress = strings.ToLower(compress); compress {
+	case CompressNone, "none":
+		return CompressNone, nil
+	case CompressGzip, CompressZstd:
+		return compress, nil
+	default:
+		return "", errors.New(i18n.T("cp.invalid_compress", compress))
+	}
+}
+
+// 打包传输，通过 exec 会话执行远程的 tar，避免 SFTP 逐个文件往返
+// 下载：远程 tar cf - | 压缩，本地解压解包
+// 上传：本地打包压缩，远程 解压 | tar xf -
+// 目标为目录，不存在时自动创建；zstd 需要本地及远程都安装 zstd 命令
+func (cp *Cp) runTar() {
+	if cp.target.server != nil {
+		for _, source := range cp.sources {
+			if source.server != nil {
+				cp.printFileError(source.raw, errors.New(i18n.T("cp.tar_remote_to_remote")))
+				return
+			}
+		}
+
+		cp.tarUpload()
+		return
+	}
+
+	for _, source := range cp.sources {
+		files, err := cp.expandRemoteGlob(source)
+		if err != nil {
+			cp.printFileError(source.raw, err)
+			continue
+		}
+
+		for _, file := range files {
+			if err := cp.tarDownload(source.server, file); err != nil {
+				cp.printFileError(file, err)
+			}
+		}
+	}
+}
+
+// 展开远程通配符，不含通配符时不使用 SFTP
+func (cp *Cp) expandRemoteGlob(source *TransferObject) ([]string, error) {
+	if !strings.ContainsAny(source.path, "*?[") {
+		return []string{source.path}, nil
+	}
+
+	sftpClient, err := cp.getSftpClient(source.server)
+	if err != nil {
+		return nil, err
+	}
+
+	return expandGlob(&SftpIOClient{SftpClient: sftpClient}, source.path)
+}
+
+// 获取 SSH Client，会话中复制时复用当前连接
+func (cp *Cp) getSshClient(server *Server) (*ssh.Client, error) {
+	if client, ok := cp.sshClients[server]; ok {
+		return client, nil
+	}
+
+	client, err := server.GetSshClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if cp.sshClients == nil {
+		cp.sshClients = make(map[*Server]*ssh.Client)
+	}
+	cp.sshClients[server] = client
+
+	return client, nil
+}
+
+// 下载 src 到本地目标目录
+func (cp *Cp) tarDownload(server *Server, src string) error {
+	client, err := cp.getSshClient(server)
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	cmd := tarCreateCmd(src, cp.excludes, cp.compress)
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	progress := newTarProgress(0)
+	defer progress.stop()
+
+	reader, closeReader, err := decompressReader(&countingReader{r: stdout, counter: progress.wire}, cp.compress)
+	if err != nil {
+		return err
+	}
+
+	extractErr := cp.extractTar(reader, cp.target.path, progress)
+	_ = closeReader()
+	// 解包失败时丢弃剩余的输出，以便远程命令结束
+	_, _ = io.Copy(ioutil.Discard, stdout)
+
+	if err := session.Wait(); err != nil {
+		return remoteError(err, &stderr)
+	}
+
+	return extractErr
+}
+
+// 上传所有源到远程目标目录
+func (cp *Cp) tarUpload() {
+	client, err := cp.getSshClient(cp.target.server)
+	if err != nil {
+		cp.printFileError(cp.target.raw, err)
+		return
+	}
+
+	files := make([]string, 0)
+	var total int64
+	for _, source := range cp.sources {
+		matches, err := expandGlob(new(LocalIOClient), source.path)
+		if err != nil {
+			cp.printFileError(source.path, err)
+			continue
+		}
+
+		for _, file := range matches {
+			size, err := cp.localTreeSize(file)
+			if err != nil {
+				cp.printFileError(file, err)
+				continue
+			}
+			total += size
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		cp.printFileError(cp.target.raw, err)
+		return
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		cp.printFileError(cp.target.raw, err)
+		return
+	}
+
+	if err := session.Start(tarExtractCmd(cp.target.path, cp.compress)); err != nil {
+		cp.printFileError(cp.target.raw, err)
+		return
+	}
+
+	progress := newTarProgress(total)
+	err = cp.writeTar(io.MultiWriter(stdin, progress.wire), files, progress)
+	_ = stdin.Close()
+	progress.stop()
+
+	if waitErr := session.Wait(); waitErr != nil {
+		cp.printFileError(cp.target.raw, remoteError(waitErr, &stderr))
+	} else if err != nil {
+		cp.printFileError(cp.target.raw, err)
+	}
+}
+
+// 打包压缩后写入 w
+func (cp *Cp) writeTar(w io.Writer, files []string, progress *tarProgress) error {
+	compressed, closeWriter, err := compressWriter(w, cp.compress)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(compressed)
+	for _, file := range files {
+		if err := cp.addTarTree(tw, file, progress); err != nil {
+			_ = tw.Close()
+			_ = closeWriter()
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		_ = closeWriter()
+		return err
+	}
+
+	return closeWriter()
+}
+
+// 遍历本地文件，统计需要传输的大小
+func (cp *Cp) localTreeSize(root string) (int64, error) {
+	var total int64
+	err := cp.walkLocal(root, func(file string, name string, info os.FileInfo) error {
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+
+	return total, err
+}
+
+// 遍历本地文件，name 为包内的路径，以 root 的文件名开头
+// 与逐个复制一致，-include/-exclude 只作用于目录内的文件
+func (cp *Cp) walkLocal(root string, fn func(file string, name string, info os.FileInfo) error) error {
+	root = filepath.Clean(root)
+	base := filepath.Base(root)
+
+	return filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "." && !cp.shouldTransfer(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return fn(file, path.Join(base, rel), info)
+	})
+}
+
+func (cp *Cp) addTarTree(tw *tar.Writer, root string, progress *tarProgress) error {
+	return cp.walkLocal(root, func(file string, name string, info os.FileInfo) error {
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := cp.copyFile(tw, f, &progress.bytes); err != nil {
+			return err
+		}
+
+		cp.stats.files++
+		cp.stats.bytes += info.Size()
+		return nil
+	})
+}
+
+// 解包到目录 dst，拒绝指向目录外的路径
+// 不支持的类型（设备文件、管道等）记为失败并继续解包
+func (cp *Cp) extractTar(r io.Reader, dst string, progress *tarProgress) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	root, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name, err := cleanTarName(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "." || !cp.shouldExtract(name, header.Typeflag == tar.TypeDir) {
+			continue
+		}
+
+		file, err := tarTargetPath(root, name)
+		if err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(file, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			// 不能通过已存在的链接写入目录外的文件
+			if err := removeExisting(file); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+
+			err = cp.copyFile(f, tr, &progress.bytes)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+
+			_ = os.Chtimes(file, header.ModTime, header.ModTime)
+			cp.stats.files++
+			cp.stats.bytes += header.Size
+		case tar.TypeLink:
+			// GNU tar 将同一 inode 的其他硬链接记录为 TypeLink，指向包内已解包的文件
+			linkName, err := cleanTarName(header.Linkname)
+			if err != nil {
+				return err
+			}
+			source, err := tarTargetPath(root, linkName)
+			if err != nil {
+				return err
+			}
+
+			size, err := linkTarFile(source, file)
+			if err != nil {
+				cp.printFileError(name, err)
+				continue
+			}
+
+			cp.stats.files++
+			cp.stats.bytes += size
+		case tar.TypeSymlink:
+			if err := removeExisting(file); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, file); err != nil {
+				return err
+			}
+		default:
+			cp.printFileError(name, errors.New(i18n.T("cp.tar_unsupported_type", string(header.Typeflag))))
+		}
+	}
+}
+
+// 检查包内路径，不能是绝对路径或包含 ..
+func cleanTarName(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.New(i18n.T("cp.tar_unsafe_path", name))
+	}
+
+	return clean, nil
+}
+
+// 包内路径对应的本地路径，创建上级目录，上级目录不能是指向 root 外的链接
+func tarTargetPath(root string, name string) (string, error) {
+	file := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+
+	parent, err := filepath.EvalSymlinks(filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+	if parent != root && !strings.HasPrefix(parent, root+string(os.PathSeparator)) {
+		return "", errors.New(i18n.T("cp.tar_unsafe_path", name))
+	}
+
+	return file, nil
+}
+
+// 删除已存在的文件或链接，已存在的目录不删除
+func removeExisting(file string) error {
+	info, err := os.Lstat(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New(i18n.T("cp.tar_is_dir", file))
+	}
+
+	return os.Remove(file)
+}
+
+// 创建硬链接，不支持时复制文件，source 必须是已解包的普通文件
+func linkTarFile(source string, file string) (int64, error) {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, errors.New(i18n.T("cp.tar_bad_link", source))
+	}
+
+	if err := removeExisting(file); err != nil {
+		return 0, err
+	}
+	if err := os.Link(source, file); err == nil {
+		return info.Size(), nil
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	return io.Copy(dst, src)
+}
+
+// 解包时按 -include/-exclude 过滤，name 以源的文件名开头，被排除的目录下的文件一并跳过
+func (cp *Cp) shouldExtract(name string, isDir bool) bool {
+	i := strings.Index(name, "/")
+	if i == -1 {
+		return true
+	}
+
+	rel := name[i+1:]
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if cp.excludes.match(dir) {
+			return false
+		}
+	}
+
+	return cp.shouldTransfer(rel, isDir)
+}
+
+// 远程打包命令
+func tarCreateCmd(src string, excludes []string, compress string) string {
+	src = strings.TrimRight(src, "/")
+	if src == "" {
+		src = "/"
+	}
+
+	cmd := "tar cf -"
+	for _, exclude := range excludes {
+		cmd += " --exclude=" + utils.ShellQuote(strings.TrimPrefix(exclude, "/"))
+	}
+	cmd += " -C " + utils.ShellQuote(path.Dir(src)) + " " + utils.ShellQuote(path.Base(src))
+
+	switch compress {
+	case CompressGzip:
+		cmd += " | gzip -c"
+	case CompressZstd:
+		cmd += " | zstd -c -q"
+	}
+
+	return cmd
+}
+
+// 远程解包命令
+func tarExtractCmd(dst string, compress string) string {
+	dst = utils.ShellQuote(dst)
+	cmd := "mkdir -p " + dst + " && cd " + dst + " && "
+
+	switch compress {
+	case CompressGzip:
+		cmd += "gzip -dc | "
+	case CompressZstd:
+		cmd += "zstd -dc -q | "
+	}
+
+	return cmd + "tar xf -"
+}
+
+// 压缩，zstd 使用本地的 zstd 命令
+func compressWriter(w io.Writer, compress string) (io.Writer, func() error, error) {
+	switch compress {
+	case CompressGzip:
+		gw := gzip.NewWriter(w)
+		return gw, gw.Close, nil
+	case CompressZstd:
+		cmd := exec.Command("zstd", "-c", "-q")
+		cmd.Stdout = w
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, errors.New(i18n.T("cp.zstd_not_found", err))
+		}
+
+		return stdin, func() error {
+			_ = stdin.Close()
+			return cmd.Wait()
+		}, nil
+	default:
+		return w, func() error { return nil }, nil
+	}
+}
+
+// 解压，zstd 使用本地的 zstd 命令
+func decompressReader(r io.Reader, compress string) (io.Reader, func() error, error) {
+	switch compress {
+	case CompressGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gr, gr.Close, nil
+	case CompressZstd:
+		cmd := exec.Command("zstd", "-dc", "-q")
+		cmd.Stdin = r
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, errors.New(i18n.T("cp.zstd_not_found", err))
+		}
+
+		return stdout, func() error {
+			_, _ = io.Copy(ioutil.Discard, stdout)
+			return cmd.Wait()
+		}, nil
+	default:
+		return r, func() error { return nil }, nil
+	}
+}
+
+// 远程命令失败时附带标准错误的内容
+func remoteError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *byteCounter
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.r.Read(p)
+	_, _ = reader.counter.Write(p[:n])
+	return n, err
+}
+
+// 打包传输的进度，bytes 为文件内容的字节数，wire 为压缩后实际传输的字节数
+type tarProgress struct {
+	total   int64 // 文件总大小，下载时未知为0
+	bytes   int64
+	wire    *byteCounter
+	started time.Time
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newTarProgress(total int64) *tarProgress {
+	progress := &tarProgress{
+		total:   total,
+		wire:    new(byteCounter),
+		started: time.Now(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(progress.stopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			progress.print()
+			select {
+			case <-ticker.C:
+			case <-progress.done:
+				return
+			}
+		}
+	}()
+
+	return progress
+}
+
+func (progress *tarProgress) stop() {
+	select {
+	case <-progress.done:
+		return
+	default:
+	}
+
+	close(progress.done)
+	<-progress.stopped
+	progress.print()
+	fmt.Println("")
+}
+
+func (progress *tarProgress) print() {
+	elapsed := time.Since(progress.started)
+	transferred := atomic.LoadInt64(&progress.bytes)
+	wire := progress.wire.count()
+
+	speed := 0.0
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		speed = float64(wire) / seconds
+	}
+
+	size := utils.SizeFormat(float64(transferred))
+	if progress.total > 0 {
+		size += "/" + utils.SizeFormat(float64(progress.total))
+	}
+
+	fmt.Print("\r\x1b[K" + i18n.T("cp.tar_progress", size, utils.SizeFormat(float64(wire)), utils.SizeFormat(speed), formatClock(elapsed)))
+}
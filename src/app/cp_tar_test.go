@@ -0,0 +1,168 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarRoundTrip(t *testing.T) {
+	src, err := ioutil.TempDir("", "autossh-tar-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "autossh-tar-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	root := filepath.Join(src, "site")
+	for file, content := range map[string]string{"index.html": "<html>", "css/a.css": "body{}", "tmp/cache": "x"} {
+		file = filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cp := Cp{compress: CompressGzip, excludes: patternsFlag{"tmp"}}
+	progress := &tarProgress{wire: new(byteCounter)}
+
+	var archive bytes.Buffer
+	if err := cp.writeTar(&archive, []string{root}, progress); err != nil {
+		t.Fatal(err)
+	}
+	if cp.stats.files != 2 || cp.stats.bytes != 12 {
+		t.Errorf("stats = %+v", cp.stats)
+	}
+
+	reader, closeReader, err := decompressReader(&archive, CompressGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Cp{}).extractTar(reader, dst, progress); err != nil {
+		t.Fatal(err)
+	}
+	_ = closeReader()
+
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "site", "css", "a.css")); err != nil || string(b) != "body{}" {
+		t.Errorf("a.css = %q %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "site", "tmp")); !os.IsNotExist(err) {
+		t.Errorf("tmp should be excluded: %v", err)
+	}
+}
+
+func TestExtractTarUnsafe(t *testing.T) {
+	dst, err := ioutil.TempDir("", "autossh-tar-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	victim := filepath.Join(dst, "victim")
+	if err := ioutil.WriteFile(victim, []byte("safe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dst, "out")
+
+	cases := [][]*tar.Header{
+		{{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "a/link", Typeflag: tar.TypeSymlink, Linkname: os.TempDir()}, {Name: "a/link/evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "../escape", Typeflag: tar.TypeLink, Linkname: "site/a"}},
+		{{Name: "site/h", Typeflag: tar.TypeLink, Linkname: "../victim"}},
+	}
+
+	for _, headers := range cases {
+		archive := newTestTar(t, headers, nil)
+		if err := (&Cp{}).extractTar(archive, out, &tarProgress{wire: new(byteCounter)}); err == nil {
+			t.Errorf("%s should be rejected", headers[len(headers)-1].Name)
+		}
+	}
+
+	// 先写入指向目录外的链接，再写入同名文件时不能覆盖链接指向的文件
+	headers := []*tar.Header{
+		{Name: "site/x", Typeflag: tar.TypeSymlink, Linkname: victim},
+		{Name: "site/x", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+	}
+	archive := newTestTar(t, headers, map[string]string{"site/x": "PWNED"})
+	if err := (&Cp{}).extractTar(archive, out, &tarProgress{wire: new(byteCounter)}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(victim); string(b) != "safe" {
+		t.Errorf("victim overwritten: %q", b)
+	}
+	if info, err := os.Lstat(filepath.Join(out, "site", "x")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("site/x should be a regular file: %v", err)
+	}
+}
+
+func TestExtractTarLinks(t *testing.T) {
+	dst, err := ioutil.TempDir("", "autossh-tar-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	headers := []*tar.Header{
+		{Name: "site/a", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		{Name: "site/b", Typeflag: tar.TypeLink, Linkname: "site/a"},
+		{Name: "site/fifo", Typeflag: tar.TypeFifo, Mode: 0644},
+	}
+	archive := newTestTar(t, headers, map[string]string{"site/a": "hello"})
+
+	cp := &Cp{}
+	if err := cp.extractTar(archive, dst, &tarProgress{wire: new(byteCounter)}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "site", "b")); err != nil || string(b) != "hello" {
+		t.Errorf("hard link = %q %v", b, err)
+	}
+	if cp.stats.files != 2 || cp.stats.bytes != 10 || cp.stats.failed != 1 {
+		t.Errorf("stats = %+v", cp.stats)
+	}
+}
+
+// 生成测试用的 tar，contents 为普通文件的内容
+func newTestTar(t *testing.T, headers []*tar.Header, contents map[string]string) *bytes.Buffer {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(contents[header.Name])); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &archive
+}
+
+func TestTarCmd(t *testing.T) {
+	cmd := tarCreateCmd("/var/www/site/", []string{"*.log"}, CompressZstd)
+	if cmd != "tar cf - --exclude='*.log' -C '/var/www' 'site' | zstd -c -q" {
+		t.Errorf("create cmd = %s", cmd)
+	}
+
+	cmd = tarExtractCmd("/srv/my site", CompressGzip)
+	if cmd != "mkdir -p '/srv/my site' && cd '/srv/my site' && gzip -dc | tar xf -" {
+		t.Errorf("extract cmd = %s", cmd)
+	}
+
+	if _, err := parseCompress("xz"); err == nil {
+		t.Error("xz should be rejected")
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"fmt"
 	"io"
@@ -8,13 +9,12 @@ import (
 
 func handleAdd(cfg *Config, _ []string) error {
 	groups := make(map[string]*Group)
-	for i := range cfg.Groups {
-		group := cfg.Groups[i]
-		groups[group.Prefix] = group
-		utils.Info("["+group.Prefix+"]"+group.GroupName, "\t")
+	for _, group := range cfg.allGroups() {
+		groups[group.path] = group
+		utils.Info("["+group.path+"]"+group.fullName(), "\t")
 	}
-	utils.Infoln("[其他值]默认组")
-	utils.Info("请输入要插入的组：")
+	utils.Infoln(i18n.T("add.default_group"))
+	utils.Info(i18n.T("add.prompt_group"))
 	g := ""
 	if _, err := fmt.Scanln(&g); err == io.EOF {
 		return nil
@@ -29,13 +29,16 @@ func handleAdd(cfg *Config, _ []string) error {
 		return err
 	}
 
-	group, ok := groups[g]
-	if ok {
-		group.Servers = append(group.Servers, server)
-		server.groupName = group.GroupName
-	} else {
-		cfg.Servers = append(cfg.Servers, &server)
+	if _, ok := groups[g]; !ok {
+		g = ""
 	}
 
+	index, err := cfg.addServer(server, g)
+	if err != nil {
+		utils.Errorln(err)
+		return nil
+	}
+
+	utils.Infoln(i18n.T("server.added", index))
 	return cfg.saveConfig(true)
 }
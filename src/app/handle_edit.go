@@ -1,29 +1,38 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"fmt"
 	"io"
 )
 
 func handleEdit(cfg *Config, args []string) error {
-	utils.Info("请输入相应序号：")
+	utils.Info(i18n.T("input.index"))
 	id := ""
-	if _, err := fmt.Scanln(&id); err == io.EOF {
+	// 直接回车取消编辑
+	if _, err := fmt.Scanln(&id); err == io.EOF || id == "" {
 		return nil
 	}
 
 	serverIndex, ok := cfg.serverIndex[id]
 	if !ok {
-		utils.Errorln("序号不存在")
+		utils.Errorln(i18n.T("input.index_not_found"))
 		return handleEdit(cfg, args)
 	}
 
-	if err := serverIndex.server.Edit(); err != nil {
+	// 在副本上编辑，校验通过后再写回
+	server := *serverIndex.server
+	if err := server.Edit(); err != nil {
 		if err == io.EOF {
 			return nil
 		}
 		return err
 	}
+
+	if err := cfg.updateServer(id, func(s *Server) { *s = server }); err != nil {
+		utils.Errorln(err)
+		return handleEdit(cfg, args)
+	}
 	return cfg.saveConfig(true)
 }
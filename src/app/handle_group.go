@@ -0,0 +1,257 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 组管理
+func handleGroup(cfg *Config, _ []string) error {
+	printGroups(cfg)
+	utils.Infoln("")
+	utils.Infoln(i18n.T("group.operations"))
+	utils.Info(i18n.T("group.prompt_operation"))
+
+	operation := ""
+	utils.Scanln(&operation)
+
+	var err error
+	switch strings.TrimSpace(operation) {
+	case "":
+		return nil
+	case "add":
+		err = handleGroupAdd(cfg)
+	case "rename":
+		err = handleGroupRename(cfg)
+	case "remove":
+		err = handleGroupRemove(cfg)
+	case "move":
+		err = handleGroupMove(cfg)
+	default:
+		utils.Errorln(i18n.T("input.invalid"))
+		return handleGroup(cfg, nil)
+	}
+
+	if err == errCanceled {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return cfg.saveConfig(true)
+}
+
+// 打印全部组，子组使用完整前缀
+func printGroups(cfg *Config) {
+	for _, group := range cfg.allGroups() {
+		utils.Info("["+group.path+"]"+group.fullName(), "\t")
+	}
+}
+
+// 取消操作
+var errCanceled = errors.New("canceled")
+
+// 读取一行输入，为空时取消操作
+func scanRequired(label string) (string, error) {
+	utils.Info(label)
+
+	ipt := ""
+	utils.Scanln(&ipt)
+	ipt = strings.TrimSpace(ipt)
+	if ipt == "" {
+		return "", errCanceled
+	}
+
+	return ipt, nil
+}
+
+// 读取组，校验失败时重新输入
+func scanGroup(cfg *Config, label string) (*Group, error) {
+	for {
+		key, err := scanRequired(label)
+		if err != nil {
+			return nil, err
+		}
+
+		if group := cfg.findGroup(key); group != nil {
+			return group, nil
+		}
+		utils.Errorln(i18n.T("group.not_found", key))
+	}
+}
+
+func handleGroupAdd(cfg *Config) error {
+	utils.Info(i18n.T("group.prompt_parent"))
+	parent := ""
+	utils.Scanln(&parent)
+	parent = strings.TrimSpace(parent)
+	if parent != "" && cfg.findGroup(parent) == nil {
+		return errors.New(i18n.T("group.not_found", parent))
+	}
+
+	name, err := scanRequired(i18n.T("group.prompt_name"))
+	if err != nil {
+		return err
+	}
+
+	for {
+		prefix, err := scanRequired(i18n.T("group.prompt_prefix"))
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.addGroup(name, prefix, parent); err != nil {
+			utils.Errorln(err)
+			continue
+		}
+
+		return nil
+	}
+}
+
+func handleGroupRename(cfg *Config) error {
+	group, err := scanGroup(cfg, i18n.T("group.prompt_rename"))
+	if err != nil {
+		return err
+	}
+
+	utils.Info(i18n.T("group.field_name") + deftVal(group.GroupName) + ":")
+	name := ""
+	utils.Scanln(&name)
+
+	for {
+		utils.Info(i18n.T("group.field_prefix") + deftVal(group.Prefix) + ":")
+		prefix := ""
+		utils.Scanln(&prefix)
+
+		if err := cfg.renameGroup(group.path, strings.TrimSpace(name), strings.TrimSpace(prefix)); err != nil {
+			utils.Errorln(err)
+			continue
+		}
+
+		return nil
+	}
+}
+
+func handleGroupRemove(cfg *Config) error {
+	group, err := scanGroup(cfg, i18n.T("group.prompt_remove"))
+	if err != nil {
+		return err
+	}
+
+	if len(group.Servers) == 0 {
+		return cfg.removeGroup(group.path, GroupServersRefuse, "")
+	}
+
+	utils.Infoln(i18n.T("group.remove_servers", len(group.Servers)))
+	for {
+		action, err := scanRequired(i18n.T("group.prompt_choose"))
+		if err != nil {
+			return err
+		}
+
+		switch action {
+		case "delete":
+			err = cfg.removeGroup(group.path, GroupServersDelete, "")
+		case "ungroup":
+			err = cfg.removeGroup(group.path, GroupServersUngroup, "")
+		default:
+			err = cfg.removeGroup(group.path, GroupServersMove, action)
+		}
+
+		if err != nil {
+			utils.Errorln(err)
+			continue
+		}
+
+		return nil
+	}
+}
+
+func handleGroupMove(cfg *Config) error {
+	group, err := scanGroup(cfg, i18n.T("group.prompt_move"))
+	if err != nil {
+		return err
+	}
+
+	for {
+		ipt, err := scanRequired(i18n.T("group.prompt_position", len(*cfg.siblings(group))))
+		if err != nil {
+			return err
+		}
+
+		position, err := strconv.Atoi(ipt)
+		if err == nil {
+			err = cfg.moveGroup(group.path, position)
+		}
+		if err != nil {
+			utils.Errorln(err)
+			continue
+		}
+
+		return nil
+	}
+}
+
+// 移动服务器到其他组或调整顺序
+func handleMove(cfg *Config, _ []string) error {
+	var serverIndex ServerIndex
+	for {
+		id, err := scanRequired(i18n.T("move.prompt_server"))
+		if err == errCanceled {
+			return nil
+		}
+
+		if serverIndex, err = cfg.findServer(id); err == nil {
+			break
+		}
+		utils.Errorln(err)
+	}
+
+	printGroups(cfg)
+	utils.Infoln(i18n.T("move.default_group"))
+
+	current := "ungroup"
+	if serverIndex.server.group != nil {
+		current = serverIndex.server.group.path
+	}
+	utils.Info(i18n.T("move.prompt_group") + deftVal(current) + ":")
+	key := ""
+	utils.Scanln(&key)
+	key = strings.TrimSpace(key)
+
+	target := serverIndex.server.group
+	switch key {
+	case "":
+	case "ungroup":
+		target = nil
+	default:
+		if target = cfg.findGroup(key); target == nil {
+			return errors.New(i18n.T("group.not_found", key))
+		}
+	}
+
+	utils.Info(i18n.T("move.prompt_position"))
+	ipt := ""
+	utils.Scanln(&ipt)
+
+	position := 0
+	if ipt = strings.TrimSpace(ipt); ipt != "" {
+		var err error
+		if position, err = strconv.Atoi(ipt); err != nil {
+			return errors.New(i18n.T("input.position_number"))
+		}
+	}
+
+	index, err := cfg.moveServer(serverIndex.index, target, position)
+	if err != nil {
+		return err
+	}
+
+	utils.Infoln(i18n.T("move.new_index", index))
+	return cfg.saveConfig(true)
+}
@@ -1,13 +1,14 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"fmt"
 	"io"
 )
 
 func handleRemove(cfg *Config, args []string) error {
-	utils.Info("请输入相应序号：")
+	utils.Info(i18n.T("input.index"))
 
 	id := ""
 	_, err := fmt.Scanln(&id)
@@ -15,19 +16,13 @@ func handleRemove(cfg *Config, args []string) error {
 		return nil
 	}
 
-	serverIndex, ok := cfg.serverIndex[id]
-	if !ok {
-		utils.Errorln("序号不存在")
+	if _, ok := cfg.serverIndex[id]; !ok {
+		utils.Errorln(i18n.T("input.index_not_found"))
 		return handleRemove(cfg, args)
 	}
 
-	if serverIndex.indexType == IndexTypeServer {
-		servers := cfg.Servers
-		cfg.Servers = append(servers[:serverIndex.serverIndex], servers[serverIndex.serverIndex+1:]...)
-	} else {
-		servers := cfg.Groups[serverIndex.groupIndex].Servers
-		servers = append(servers[:serverIndex.serverIndex], servers[serverIndex.serverIndex+1:]...)
-		cfg.Groups[serverIndex.groupIndex].Servers = servers
+	if err := cfg.removeServer(id); err != nil {
+		return err
 	}
 
 	return cfg.saveConfig(true)
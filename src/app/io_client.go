@@ -4,6 +4,7 @@ import (
 	"github.com/pkg/sftp"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 type IOClientType int
@@ -22,6 +23,7 @@ type IOClient interface {
 	Create(file string) (FileLike, error)
 	Open(file string) (FileLike, error)
 	ReadDir(file string) ([]os.FileInfo, error)
+	Glob(pattern string) ([]string, error)
 }
 
 // Local
@@ -48,6 +50,10 @@ func (client *LocalIOClient) ReadDir(file string) ([]os.FileInfo, error) {
 	return ioutil.ReadDir(file)
 }
 
+func (client *LocalIOClient) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
 // SFTP(Remote)
 type SftpIOClient struct {
 	SftpClient *sftp.Client
@@ -72,3 +78,7 @@ func (client *SftpIOClient) Open(file string) (FileLike, error) {
 func (client *SftpIOClient) ReadDir(file string) ([]os.FileInfo, error) {
 	return client.SftpClient.ReadDir(file)
 }
+
+func (client *SftpIOClient) Glob(pattern string) ([]string, error) {
+	return client.SftpClient.Glob(pattern)
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"encoding/json"
 	"github.com/pkg/errors"
@@ -25,7 +26,17 @@ func loadConfig(configFile string) (cfg *Config, err error) {
 	}
 
 	cfg.file = configFile
-	cfg.createServerIndex()
+	if cfg.Language != "" {
+		i18n.SetLanguage(cfg.Language)
+	}
+
+	if err := cfg.createServerIndex(); err != nil {
+		return cfg, err
+	}
+
+	for _, alias := range cfg.commandAliases() {
+		utils.Warnln(i18n.T("config.alias_command", alias))
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,342 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultControlPersist = 600 // 主连接空闲保持秒数
+	muxRequestExit        = "exit@autossh"
+)
+
+// 是否启用连接复用，对应选项 ControlMaster
+func (server *Server) muxEnabled() bool {
+	enabled, _ := server.Options["ControlMaster"].(bool)
+	return enabled
+}
+
+// 主连接空闲保持时间，对应选项 ControlPersist（秒）
+func (server *Server) controlPersist() time.Duration {
+	if val, ok := server.Options["ControlPersist"].(float64); ok && val > 0 {
+		return time.Duration(val) * time.Second
+	}
+
+	return defaultControlPersist * time.Second
+}
+
+// 主连接的本地socket路径
+func (server *Server) controlPath() (string, error) {
+	dir, err := utils.ParsePath("~/.autossh/mux")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(server.User + "@" + server.Ip + ":" + strconv.Itoa(server.Port)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".sock"), nil
+}
+
+// 通过主连接获取SSH Client，主连接不存在时在后台启动
+func (server *Server) muxClient() (*ssh.Client, error) {
+	path, err := server.controlPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if client, err := dialMux(path, server.User); err == nil {
+		return client, nil
+	}
+
+	if err := server.startMuxMaster(); err != nil {
+		return nil, err
+	}
+
+	return dialMux(path, server.User)
+}
+
+// 连接本地主连接
+func dialMux(path string, user string) (*ssh.Client, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User: user,
+		// socket仅当前用户可访问，无需校验
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		},
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, path, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// 启动后台主连接进程，等待其就绪
+func (server *Server) startMuxMaster() error {
+	if server.index == "" {
+		return errors.New("server index not found")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "-c", c, "mux", "master", server.index)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	_ = cmd.Process.Release()
+
+	if line = strings.TrimSpace(line); line != "ok" {
+		return errors.New(i18n.T("mux.start_failed", line))
+	}
+
+	return nil
+}
+
+// 主连接
+// 在本地socket上运行一个SSH服务，将收到的通道及请求转发到远程连接上
+type muxMaster struct {
+	server   *Server
+	path     string
+	client   *ssh.Client
+	config   *ssh.ServerConfig
+	listener net.Listener
+
+	mu     sync.Mutex
+	active int
+	idle   *time.Timer
+	exit   chan struct{}
+	once   sync.Once
+}
+
+// 运行主连接，就绪后向标准输出写入 ok
+func runMuxMaster(cfg *Config, index string) error {
+	serverIndex, ok := cfg.serverIndex[index]
+	if !ok {
+		return errors.New(i18n.T("server.not_found", index))
+	}
+
+	server := serverIndex.server
+	path, err := server.controlPath()
+	if err != nil {
+		return err
+	}
+
+	client, err := server.dialSshClient()
+	if err != nil {
+		return err
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	_ = os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	master := &muxMaster{
+		server:   server,
+		path:     path,
+		client:   client,
+		config:   &ssh.ServerConfig{NoClientAuth: true},
+		listener: listener,
+		exit:     make(chan struct{}),
+	}
+	master.config.AddHostKey(signer)
+	master.idle = time.AfterFunc(server.controlPersist(), master.stop)
+
+	fmt.Println("ok")
+	_ = os.Stdout.Close()
+
+	master.serve()
+	return nil
+}
+
+func (master *muxMaster) serve() {
+	go func() {
+		for {
+			conn, err := master.listener.Accept()
+			if err != nil {
+				master.stop()
+				return
+			}
+
+			go master.handleConn(conn)
+		}
+	}()
+
+	go func() {
+		_ = master.client.Wait()
+		master.stop()
+	}()
+
+	<-master.exit
+
+	_ = master.listener.Close()
+	_ = os.Remove(master.path)
+	_ = master.client.Close()
+}
+
+func (master *muxMaster) stop() {
+	master.once.Do(func() {
+		close(master.exit)
+	})
+}
+
+// 处理本地连接
+func (master *muxMaster) handleConn(conn net.Conn) {
+	master.mu.Lock()
+	master.active++
+	master.idle.Stop()
+	master.mu.Unlock()
+
+	defer func() {
+		master.mu.Lock()
+		master.active--
+		if master.active == 0 {
+			master.idle.Reset(master.server.controlPersist())
+		}
+		master.mu.Unlock()
+	}()
+
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, master.config)
+	if err != nil {
+		utils.Logger.Category("mux").Error("handshake fail ", err)
+		return
+	}
+	defer sconn.Close()
+
+	go master.handleGlobalRequests(reqs)
+
+	for newChannel := range chans {
+		go master.handleChannel(newChannel)
+	}
+}
+
+// 转发全局请求，exit 请求用于关闭主连接
+func (master *muxMaster) handleGlobalRequests(reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if req.Type == muxRequestExit {
+			_ = req.Reply(true, nil)
+			master.stop()
+			continue
+		}
+
+		ok, payload, err := master.client.SendRequest(req.Type, req.WantReply, req.Payload)
+		if req.WantReply {
+			_ = req.Reply(ok && err == nil, payload)
+		}
+	}
+}
+
+// 在远程连接上打开同类型通道，双向转发数据及请求
+func (master *muxMaster) handleChannel(newChannel ssh.NewChannel) {
+	upChannel, upReqs, err := master.client.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
+	if err != nil {
+		if openErr, ok := err.(*ssh.OpenChannelError); ok {
+			_ = newChannel.Reject(openErr.Reason, openErr.Message)
+		} else {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+
+	downChannel, downReqs, err := newChannel.Accept()
+	if err != nil {
+		_ = upChannel.Close()
+		return
+	}
+
+	// 远程 -> 本地
+	var copyDown sync.WaitGroup
+	copyDown.Add(2)
+	go func() {
+		defer copyDown.Done()
+		_, _ = io.Copy(downChannel, upChannel)
+	}()
+	go func() {
+		defer copyDown.Done()
+		_, _ = io.Copy(downChannel.Stderr(), upChannel.Stderr())
+	}()
+
+	// 本地 -> 远程
+	go func() {
+		_, _ = io.Copy(upChannel, downChannel)
+		_ = upChannel.CloseWrite()
+	}()
+
+	// 本地通道关闭后关闭远程通道
+	go func() {
+		forwardChannelRequests(downReqs, upChannel)
+		_ = upChannel.Close()
+	}()
+
+	// 远程通道关闭后，等待数据转发完毕再关闭本地通道，确保 exit-status 等请求先于关闭送达
+	forwardChannelRequests(upReqs, downChannel)
+	copyDown.Wait()
+	_ = downChannel.CloseWrite()
+	_ = downChannel.Close()
+	_ = upChannel.Close()
+}
+
+func forwardChannelRequests(reqs <-chan *ssh.Request, channel ssh.Channel) {
+	for req := range reqs {
+		ok, err := channel.SendRequest(req.Type, req.WantReply, req.Payload)
+		if req.WantReply {
+			_ = req.Reply(ok && err == nil, nil)
+		}
+	}
+}
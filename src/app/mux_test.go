@@ -0,0 +1,168 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 启动一个测试用SSH服务，exec 请求会原样输出命令内容，shell 请求保持会话不结束
+func startTestSshServer(t *testing.T, password string) net.Listener {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+
+					go func() {
+						for req := range requests {
+							if req.Type == "shell" {
+								_ = req.Reply(true, nil)
+								continue
+							}
+							if req.Type != "exec" {
+								_ = req.Reply(false, nil)
+								continue
+							}
+
+							_ = req.Reply(true, nil)
+							_, _ = channel.Write(req.Payload[4:])
+							status := make([]byte, 4)
+							binary.BigEndian.PutUint32(status, 0)
+							_, _ = channel.SendRequest("exit-status", false, status)
+							_ = channel.Close()
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return listener
+}
+
+func TestMuxMaster(t *testing.T) {
+	sshListener := startTestSshServer(t, "secret")
+	defer sshListener.Close()
+
+	addr := sshListener.Addr().(*net.TCPAddr)
+	server := &Server{Ip: addr.IP.String(), Port: addr.Port, User: "test", Password: "secret", Method: "password"}
+
+	client, err := server.dialSshClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "autossh-mux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(privateKey)
+	master := &muxMaster{
+		server:   server,
+		path:     path,
+		client:   client,
+		config:   &ssh.ServerConfig{NoClientAuth: true},
+		listener: listener,
+		exit:     make(chan struct{}),
+	}
+	master.config.AddHostKey(signer)
+	master.idle = time.AfterFunc(time.Minute, master.stop)
+
+	done := make(chan struct{})
+	go func() {
+		master.serve()
+		close(done)
+	}()
+
+	for i := 0; i < 2; i++ {
+		muxClient, err := dialMux(path, server.User)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session, err := muxClient.NewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := "echo " + strconv.Itoa(i)
+		output, err := session.Output(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != cmd {
+			t.Errorf("output = %q, want %q", output, cmd)
+		}
+		_ = muxClient.Close()
+	}
+
+	muxClient, err := dialMux(path, server.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := muxClient.SendRequest(muxRequestExit, true, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("master did not exit")
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 通过本地命令建立连接，与 OpenSSH 的 ProxyCommand 一致
+// 命令的标准输入输出作为SSH传输通道，支持占位符：%h-主机，%p-端口，%r-用户，%%-百分号
+type commandDialer struct {
+	command string
+	user    string
+}
+
+func (dialer *commandDialer) Dial(network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	command := strings.NewReplacer("%%", "%", "%h", host, "%p", port, "%r", dialer.user).Replace(dialer.command)
+
+	// 使用 os.Pipe 以支持读写超时
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdinReader.Close()
+		_ = stdinWriter.Close()
+		return nil, err
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = stdinReader
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Start()
+	_ = stdinReader.Close()
+	_ = stdoutWriter.Close()
+	if err != nil {
+		_ = stdinWriter.Close()
+		_ = stdoutReader.Close()
+		return nil, err
+	}
+
+	return &commandConn{
+		cmd:    cmd,
+		stdin:  stdinWriter,
+		stdout: stdoutReader,
+		addr:   commandAddr(command),
+	}, nil
+}
+
+// 命令连接，实现 net.Conn
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  *os.File
+	stdout *os.File
+	addr   commandAddr
+}
+
+func (conn *commandConn) Read(b []byte) (int, error) {
+	return conn.stdout.Read(b)
+}
+
+func (conn *commandConn) Write(b []byte) (int, error) {
+	return conn.stdin.Write(b)
+}
+
+func (conn *commandConn) Close() error {
+	_ = conn.stdin.Close()
+	_ = conn.stdout.Close()
+
+	if conn.cmd.Process != nil {
+		_ = conn.cmd.Process.Kill()
+	}
+	_ = conn.cmd.Wait()
+
+	return nil
+}
+
+func (conn *commandConn) LocalAddr() net.Addr {
+	return conn.addr
+}
+
+func (conn *commandConn) RemoteAddr() net.Addr {
+	return conn.addr
+}
+
+func (conn *commandConn) SetDeadline(t time.Time) error {
+	if err := conn.stdin.SetDeadline(t); err != nil {
+		return err
+	}
+	return conn.stdout.SetDeadline(t)
+}
+
+func (conn *commandConn) SetReadDeadline(t time.Time) error {
+	return conn.stdout.SetReadDeadline(t)
+}
+
+func (conn *commandConn) SetWriteDeadline(t time.Time) error {
+	return conn.stdin.SetWriteDeadline(t)
+}
+
+type commandAddr string
+
+func (addr commandAddr) Network() string {
+	return "proxy-command"
+}
+
+func (addr commandAddr) String() string {
+	return string(addr)
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"time"
+)
+
+// 限速，按令牌桶计算，最多积累1秒的流量
+type rateLimiter struct {
+	limit  int64 // 每秒字节数，不大于0时不限速
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newRateLimiter(limit int64) *rateLimiter {
+	return &rateLimiter{
+		limit: limit,
+		last:  time.Now(),
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+// 消耗n字节，超出限速时等待
+func (limiter *rateLimiter) wait(n int) {
+	if limiter == nil || limiter.limit <= 0 {
+		return
+	}
+
+	now := limiter.now()
+	limit := float64(limiter.limit)
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limit
+	if limiter.tokens > limit {
+		limiter.tokens = limit
+	}
+	limiter.last = now
+
+	limiter.tokens -= float64(n)
+	if limiter.tokens < 0 {
+		limiter.sleep(time.Duration(-limiter.tokens / limit * float64(time.Second)))
+	}
+}
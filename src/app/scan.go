@@ -1,6 +1,7 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"strings"
 )
@@ -36,7 +37,7 @@ func scanInput(cfg *Config) (loop bool, clear bool, reload bool) {
 	case InputCmdServer:
 		{
 			server := cfg.serverIndex[cmd].server
-			utils.Infoln("你选择了", server.Name)
+			utils.Infoln(i18n.T("scan.selected", server.Name))
 			err := server.Connect()
 			if err != nil {
 				utils.Logger.Error("server connect error ", err)
@@ -46,11 +47,11 @@ func scanInput(cfg *Config) (loop bool, clear bool, reload bool) {
 		}
 	case InputCmdGroupPrefix:
 		{
-			group := cfg.Groups[extInfo.(int)]
+			group := extInfo.(*Group)
 			group.Collapse = !group.Collapse
 			err := cfg.saveConfig(false)
 			if err != nil {
-				utils.Errorln("备份失败", err)
+				utils.Errorln(i18n.T("scan.backup_failed"), err)
 				loop = false
 				return
 			} else {
@@ -75,6 +76,8 @@ func checkInput(cfg *Config) (cmd string, inputCmd int, extInfo interface{}) {
 			defaultServer = ""
 			skipOpt = true
 		}
+		// 已获得输入，后台检测完成时不再重绘菜单
+		menuStatus.setWaiting(false)
 
 		ipts := strings.Split(ipt, " ")
 		cmd = ipts[0]
@@ -92,20 +95,20 @@ func checkInput(cfg *Config) (cmd string, inputCmd int, extInfo interface{}) {
 			break
 		}
 
-		groupIndex := -1
-		for index, group := range cfg.Groups {
-			if group.Prefix == cmd {
+		var matched *Group
+		for _, group := range cfg.allGroups() {
+			if group.path == cmd {
 				inputCmd = InputCmdGroupPrefix
-				groupIndex = index
-				extInfo = index
+				matched = group
+				extInfo = group
 				break
 			}
 		}
-		if groupIndex != -1 {
+		if matched != nil {
 			break
 		}
 
-		utils.Errorln("输入有误，请重新输入")
+		utils.Errorln(i18n.T("input.invalid"))
 	}
 
 	return cmd, inputCmd, extInfo
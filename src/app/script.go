@@ -0,0 +1,303 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultScriptTimeout = 10
+	watcherBufferSize    = 8 * 1024
+
+	ScriptOnTimeoutAbort    = "abort"    // 终止脚本，交由用户操作（默认）
+	ScriptOnTimeoutContinue = "continue" // 跳过当前步骤继续执行
+)
+
+// 自动化脚本
+// 按顺序执行步骤：等待输出匹配正则，再发送响应，全部执行完毕后交由用户操作
+type Script struct {
+	Vars    map[string]string `json:"vars"`    // 变量，可在 send/set 中通过 ${name} 引用
+	Timeout int               `json:"timeout"` // 默认等待超时秒数
+	Steps   []ScriptStep      `json:"steps"`
+}
+
+type ScriptStep struct {
+	Expect    string            `json:"expect"`     // 等待匹配的正则，为空则不等待
+	Send      string            `json:"send"`       // 匹配后发送的内容
+	Raw       bool              `json:"raw"`        // 为true时不自动追加回车
+	Set       map[string]string `json:"set"`        // 匹配后设置变量，可通过 $1、$2 引用正则分组
+	Cases     []ScriptCase      `json:"cases"`      // 多分支匹配，按先匹配到的分支发送
+	Timeout   int               `json:"timeout"`    // 等待超时秒数
+	OnTimeout string            `json:"on_timeout"` // abort / continue
+}
+
+// 分支，Expect 为空的分支为默认分支，其他分支均未匹配且等待超时后使用
+type ScriptCase struct {
+	Expect string            `json:"expect"`
+	Send   string            `json:"send"`
+	Raw    bool              `json:"raw"`
+	Set    map[string]string `json:"set"`
+}
+
+// 登录后自动执行的步骤，是脚本步骤的简化形式
+// 先等待输出中出现 Expect（为空则不等待），再发送 Send 并回车
+type StartupStep struct {
+	Expect  string `json:"expect"`
+	Send    string `json:"send"`
+	Timeout int    `json:"timeout"` // 等待超时秒数，默认10秒
+}
+
+// 转换为脚本步骤，Expect 按普通字符串匹配，Send 中仅替换 ${password}
+func (step StartupStep) scriptStep() ScriptStep {
+	expect := ""
+	if step.Expect != "" {
+		expect = regexp.QuoteMeta(step.Expect)
+	}
+
+	return ScriptStep{
+		Expect:  expect,
+		Send:    strings.ReplaceAll(strings.ReplaceAll(step.Send, "$", "$$"), "$${password}", "${password}"),
+		Timeout: step.Timeout,
+	}
+}
+
+// 脚本执行器
+type scriptRunner struct {
+	script  *Script
+	vars    map[string]string
+	stdin   io.Writer
+	watcher *outputWatcher
+}
+
+// 创建脚本执行器，内置变量 user、ip、name、password
+func newScriptRunner(server *Server, script *Script, stdin io.Writer, watcher *outputWatcher) *scriptRunner {
+	// 连接时已解析 password_source，此处使用缓存
+	password, _ := server.password()
+	vars := map[string]string{
+		"user":     server.User,
+		"ip":       server.Ip,
+		"name":     server.Name,
+		"password": password,
+	}
+	for k, v := range script.Vars {
+		vars[k] = v
+	}
+
+	return &scriptRunner{
+		script:  script,
+		vars:    vars,
+		stdin:   stdin,
+		watcher: watcher,
+	}
+}
+
+// 执行脚本
+func (runner *scriptRunner) run() error {
+	for i, step := range runner.script.Steps {
+		cases := step.Cases
+		if len(cases) == 0 {
+			cases = []ScriptCase{{Expect: step.Expect, Send: step.Send, Raw: step.Raw, Set: step.Set}}
+		}
+
+		matched, groups, err := runner.expect(cases, runner.timeout(step))
+		if err != nil {
+			if err == errScriptTimeout && step.OnTimeout == ScriptOnTimeoutContinue {
+				continue
+			}
+			return errors.New("step " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+
+		c := cases[matched]
+		for k, v := range c.Set {
+			runner.vars[k] = runner.expand(v, groups)
+		}
+
+		if c.Send == "" {
+			continue
+		}
+
+		send := runner.expand(c.Send, groups)
+		if !c.Raw {
+			send += "\r"
+		}
+
+		if _, err := runner.stdin.Write([]byte(send)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (runner *scriptRunner) timeout(step ScriptStep) time.Duration {
+	timeout := step.Timeout
+	if timeout <= 0 {
+		timeout = runner.script.Timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+
+	return time.Duration(timeout) * time.Second
+}
+
+// 等待任一分支匹配，返回分支序号及正则分组
+// 只有默认分支时不等待，否则超时后使用默认分支
+func (runner *scriptRunner) expect(cases []ScriptCase, timeout time.Duration) (int, []string, error) {
+	fallback, waiting := -1, false
+	patterns := make([]*regexp.Regexp, len(cases))
+	for i, c := range cases {
+		if c.Expect == "" {
+			if fallback == -1 {
+				fallback = i
+			}
+			continue
+		}
+
+		re, err := regexp.Compile(c.Expect)
+		if err != nil {
+			return -1, nil, err
+		}
+		patterns[i] = re
+		waiting = true
+	}
+
+	if !waiting {
+		return fallback, nil, nil
+	}
+
+	matched, groups, err := runner.watcher.expect(patterns, timeout)
+	if err == errScriptTimeout && fallback != -1 {
+		return fallback, nil, nil
+	}
+	return matched, groups, err
+}
+
+// 变量替换，$1、$2 为正则分组，$$ 为 $ 本身
+func (runner *scriptRunner) expand(str string, groups []string) string {
+	return os.Expand(str, func(key string) string {
+		if key == "$" {
+			return "$"
+		}
+
+		if i, err := strconv.Atoi(key); err == nil {
+			if i < len(groups) {
+				return groups[i]
+			}
+			return ""
+		}
+
+		return runner.vars[key]
+	})
+}
+
+// 执行服务器的自动化脚本及登录步骤
+func (server *Server) runScript(stdin io.Writer, watcher *outputWatcher) {
+	scripts := make([]*Script, 0)
+	if len(server.Startup) > 0 {
+		startup := &Script{Steps: make([]ScriptStep, len(server.Startup))}
+		for i, step := range server.Startup {
+			startup.Steps[i] = step.scriptStep()
+		}
+		scripts = append(scripts, startup)
+	}
+	if server.Script != nil {
+		scripts = append(scripts, server.Script)
+	}
+
+	for _, script := range scripts {
+		if err := newScriptRunner(server, script, stdin, watcher).run(); err != nil {
+			utils.Logger.Category("script").Error("run script fail ", err)
+			return
+		}
+	}
+}
+
+// 等待输出超时，消息在使用时按当前语言翻译
+type scriptTimeoutError struct{}
+
+func (scriptTimeoutError) Error() string {
+	return i18n.T("script.timeout")
+}
+
+var errScriptTimeout error = scriptTimeoutError{}
+
+// 监听会话输出，供脚本匹配使用
+type outputWatcher struct {
+	mu     sync.Mutex
+	buff   []byte
+	notify chan struct{}
+}
+
+func newOutputWatcher() *outputWatcher {
+	return &outputWatcher{notify: make(chan struct{}, 1)}
+}
+
+func (watcher *outputWatcher) Write(p []byte) (int, error) {
+	watcher.mu.Lock()
+	watcher.buff = append(watcher.buff, p...)
+	if len(watcher.buff) > watcherBufferSize {
+		watcher.buff = watcher.buff[len(watcher.buff)-watcherBufferSize:]
+	}
+	watcher.mu.Unlock()
+
+	select {
+	case watcher.notify <- struct{}{}:
+	default:
+	}
+
+	return len(p), nil
+}
+
+// 等待输出匹配任一正则，为 nil 的正则跳过，匹配成功后丢弃已匹配部分
+func (watcher *outputWatcher) expect(patterns []*regexp.Regexp, timeout time.Duration) (int, []string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		watcher.mu.Lock()
+		matched, end := -1, -1
+		var groups []string
+		for i, re := range patterns {
+			if re == nil {
+				continue
+			}
+
+			loc := re.FindSubmatchIndex(watcher.buff)
+			if loc == nil || (end != -1 && loc[1] >= end) {
+				continue
+			}
+
+			matched, end = i, loc[1]
+			groups = make([]string, len(loc)/2)
+			for j := range groups {
+				if loc[j*2] >= 0 {
+					groups[j] = string(watcher.buff[loc[j*2]:loc[j*2+1]])
+				}
+			}
+		}
+
+		if matched != -1 {
+			watcher.buff = watcher.buff[end:]
+		}
+		watcher.mu.Unlock()
+
+		if matched != -1 {
+			return matched, groups, nil
+		}
+
+		select {
+		case <-watcher.notify:
+		case <-timer.C:
+			return -1, nil, errScriptTimeout
+		}
+	}
+}
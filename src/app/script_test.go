@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOutputWatcher(t *testing.T) {
+	watcher := newOutputWatcher()
+	go func() {
+		_, _ = watcher.Write([]byte("Last login: today\r\n"))
+		time.Sleep(10 * time.Millisecond)
+		_, _ = watcher.Write([]byte("[root@web ~]# "))
+	}()
+
+	// 多个正则同时匹配时取结束位置最靠前的
+	patterns := []*regexp.Regexp{regexp.MustCompile(`\[(\w+)@(\w+) ~\]# $`), nil, regexp.MustCompile(`login: (\w+)`)}
+	matched, groups, err := watcher.expect(patterns, time.Second)
+	if err != nil || matched != 2 || groups[1] != "today" {
+		t.Fatalf("expect = %d %v %v", matched, groups, err)
+	}
+
+	// 已匹配的部分被丢弃
+	matched, groups, err = watcher.expect(patterns, time.Second)
+	if err != nil || matched != 0 || groups[1] != "root" || groups[2] != "web" {
+		t.Fatalf("expect = %d %v %v", matched, groups, err)
+	}
+
+	if _, _, err := watcher.expect(patterns, 10*time.Millisecond); err != errScriptTimeout {
+		t.Errorf("expect after consumed = %v, want timeout", err)
+	}
+
+	// 缓冲区只保留最近的输出
+	_, _ = watcher.Write(bytes.Repeat([]byte("x"), watcherBufferSize+10))
+	if len(watcher.buff) != watcherBufferSize {
+		t.Errorf("buffer size = %d", len(watcher.buff))
+	}
+}
+
+func TestScriptRunnerExpect(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		cases   []ScriptCase
+		matched int
+		err     bool
+	}{
+		{"sole empty expect", "", []ScriptCase{{}}, 0, false},
+		{"pattern before default", "Select [1-3]:", []ScriptCase{{Expect: ""}, {Expect: `Select \[1-3\]:`}}, 1, false},
+		{"default after timeout", "$ ", []ScriptCase{{Expect: "Password:"}, {Expect: ""}}, 1, false},
+		{"timeout without default", "$ ", []ScriptCase{{Expect: "Password:"}}, -1, true},
+		{"invalid pattern", "", []ScriptCase{{Expect: "("}}, -1, true},
+	}
+
+	for _, c := range cases {
+		runner := &scriptRunner{watcher: newOutputWatcher()}
+		_, _ = runner.watcher.Write([]byte(c.output))
+
+		matched, _, err := runner.expect(c.cases, 20*time.Millisecond)
+		if matched != c.matched || (err != nil) != c.err {
+			t.Errorf("%s: expect = %d %v, want %d", c.name, matched, err, c.matched)
+		}
+	}
+}
+
+func TestScriptRunnerRun(t *testing.T) {
+	var stdin bytes.Buffer
+	script := &Script{
+		Vars:    map[string]string{"enable_password": "s3cret"},
+		Timeout: 1,
+		Steps: []ScriptStep{
+			{Cases: []ScriptCase{{Expect: `Select \[1-3\]:`, Send: "1"}, {Expect: `[>#] ?$`, Raw: true}}},
+			{Expect: `(\S+)>\s*$`, Send: "enable", Set: map[string]string{"hostname": "$1"}},
+			{Expect: "Password:", Send: "${enable_password} ${hostname} $$1"},
+			{Expect: "never", Timeout: 1, OnTimeout: ScriptOnTimeoutContinue},
+		},
+	}
+	runner := newScriptRunner(&Server{Name: "sw"}, script, &stdin, newOutputWatcher())
+
+	go func() {
+		_, _ = runner.watcher.Write([]byte("Select [1-3]: "))
+		time.Sleep(10 * time.Millisecond)
+		_, _ = runner.watcher.Write([]byte("\r\ncore-sw1> "))
+		time.Sleep(10 * time.Millisecond)
+		_, _ = runner.watcher.Write([]byte("Password: "))
+	}()
+
+	if err := runner.run(); err != nil {
+		t.Fatal(err)
+	}
+	if stdin.String() != "1\renable\rs3cret core-sw1 $1\r" {
+		t.Errorf("sent %q", stdin.String())
+	}
+}
+
+func TestStartupScriptStep(t *testing.T) {
+	cases := []struct {
+		step   StartupStep
+		expect string
+		sent   string
+	}{
+		{StartupStep{Expect: "$ ", Send: "cd /var/log"}, `\$ `, "cd /var/log"},
+		{StartupStep{Expect: "[sudo] password for root:", Send: "${password}"}, `\[sudo\] password for root:`, "s3cret"},
+		{StartupStep{Send: "echo $HOME ${USER} $1 $$"}, "", "echo $HOME ${USER} $1 $$"},
+		{StartupStep{Send: "a${password}b$"}, "", "as3cretb$"},
+	}
+
+	runner := &scriptRunner{vars: map[string]string{"password": "s3cret", "USER": "x", "HOME": "y"}}
+	for _, c := range cases {
+		step := c.step.scriptStep()
+		if step.Expect != c.expect {
+			t.Errorf("expect = %q, want %q", step.Expect, c.expect)
+		}
+		if step.Expect != "" && !regexp.MustCompile(step.Expect).MatchString(c.step.Expect) {
+			t.Errorf("%q does not match itself", c.step.Expect)
+		}
+		if sent := runner.expand(step.Send, []string{"group0", "group1"}); sent != c.sent {
+			t.Errorf("send %q = %q, want %q", c.step.Send, sent, c.sent)
+		}
+	}
+}
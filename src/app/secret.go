@@ -0,0 +1,136 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	SecretSourceCmd     = "cmd"
+	SecretSourceEnv     = "env"
+	SecretSourceFile    = "file"
+	SecretSourceKeyring = "keyring"
+)
+
+// 解析密码来源，格式为 类型:值
+// cmd:pass show web       执行命令，取标准输出
+// env:WEB_PASSWORD        读取环境变量
+// file:~/.secrets/web     读取文件内容
+// keyring:service/account 读取系统钥匙串，macOS 使用 security，Linux 使用 secret-tool
+// 命令输出及文件内容去除末尾的换行
+func resolveSecret(source string) (string, error) {
+	kind, value, err := parseSecretSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case SecretSourceCmd:
+		return runSecretCommand(exec.Command("sh", "-c", value))
+	case SecretSourceEnv:
+		secret, ok := os.LookupEnv(value)
+		if !ok {
+			return "", errors.New(i18n.T("secret.env_not_set", value))
+		}
+		return secret, nil
+	case SecretSourceFile:
+		file, err := utils.ParsePath(value)
+		if err != nil {
+			return "", err
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	default:
+		return keyringSecret(value)
+	}
+}
+
+// 拆分密码来源的类型及值
+func parseSecretSource(source string) (string, string, error) {
+	i := strings.Index(source, ":")
+	if i == -1 || strings.TrimSpace(source[i+1:]) == "" {
+		return "", "", errors.New(i18n.T("secret.invalid_source", source))
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(source[:i]))
+	switch kind {
+	case SecretSourceCmd, SecretSourceEnv, SecretSourceFile, SecretSourceKeyring:
+		return kind, strings.TrimSpace(source[i+1:]), nil
+	default:
+		return "", "", errors.New(i18n.T("secret.invalid_source", source))
+	}
+}
+
+// 从系统钥匙串读取，value 为 service/account，account 可省略
+func keyringSecret(value string) (string, error) {
+	service, account := value, ""
+	if i := strings.LastIndex(value, "/"); i != -1 {
+		service, account = value[:i], value[i+1:]
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		args := []string{"find-generic-password", "-s", service, "-w"}
+		if account != "" {
+			args = append(args, "-a", account)
+		}
+		cmd = exec.Command("security", args...)
+	case "linux", "freebsd", "openbsd":
+		args := []string{"lookup", "service", service}
+		if account != "" {
+			args = append(args, "account", account)
+		}
+		cmd = exec.Command("secret-tool", args...)
+	default:
+		return "", errors.New(i18n.T("secret.keyring_unsupported", runtime.GOOS))
+	}
+
+	return runSecretCommand(cmd)
+}
+
+// 执行命令并取标准输出，失败时附带标准错误的内容
+func runSecretCommand(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New(i18n.T("secret.command_failed", strings.Join(cmd.Args, " "), msg))
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// 获取密码，配置了 password_source 时在首次使用时解析并缓存
+func (server *Server) password() (string, error) {
+	if server.PasswordSource == "" {
+		return server.Password, nil
+	}
+
+	if !server.secretResolved {
+		secret, err := resolveSecret(server.PasswordSource)
+		if err != nil {
+			return "", err
+		}
+		server.secret = secret
+		server.secretResolved = true
+	}
+
+	return server.secret, nil
+}
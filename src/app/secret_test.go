@@ -0,0 +1,52 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autossh-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Setenv("AUTOSSH_TEST_SECRET", "from-env")
+	defer os.Unsetenv("AUTOSSH_TEST_SECRET")
+
+	cases := map[string]string{
+		"cmd:printf 'from-cmd\\n'": "from-cmd",
+		"env:AUTOSSH_TEST_SECRET":  "from-env",
+		"file:" + file:             "from-file",
+		" CMD : echo spaced":       "spaced",
+	}
+	for source, want := range cases {
+		if got, err := resolveSecret(source); err != nil || got != want {
+			t.Errorf("resolveSecret(%q) = %q, %v, want %q", source, got, err, want)
+		}
+	}
+
+	invalid := []string{"", "plain", "cmd:", "vault:secret/web", "env:AUTOSSH_TEST_UNSET", "cmd:exit 1", "file:" + filepath.Join(dir, "missing")}
+	for _, source := range invalid {
+		if _, err := resolveSecret(source); err == nil {
+			t.Errorf("resolveSecret(%q) expected error", source)
+		}
+	}
+
+	// 解析结果缓存，不重复执行命令
+	server := &Server{Password: "plain", PasswordSource: "file:" + file}
+	if password, err := server.password(); err != nil || password != "from-file" {
+		t.Fatalf("password = %q, %v", password, err)
+	}
+	_ = os.Remove(file)
+	if password, err := server.password(); err != nil || password != "from-file" {
+		t.Errorf("cached password = %q, %v", password, err)
+	}
+}
@@ -1,13 +1,16 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
+	"bytes"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/net/proxy"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
@@ -19,6 +22,7 @@ import (
 )
 
 type Server struct {
+	Id       int                    `json:"id"` // 组内唯一，与组前缀组成编号，不随位置变化
 	Name     string                 `json:"name"`
 	Ip       string                 `json:"ip"`
 	Port     int                    `json:"port"`
@@ -26,14 +30,34 @@ type Server struct {
 	Password string                 `json:"password"`
 	Method   string                 `json:"method"`
 	Key      string                 `json:"key"`
+	Cert     string                 `json:"cert,omitempty"` // 用户证书，为空时尝试加载 密钥路径-cert.pub
 	Options  map[string]interface{} `json:"options"`
 	Alias    string                 `json:"alias"`
 	Log      ServerLog              `json:"log"`
 
-	termWidth  int
-	termHeight int
-	groupName  string
-	group      *Group
+	RemoteCommand string        `json:"remote_command"` // 替代登录shell执行的命令
+	Startup       []StartupStep `json:"startup"`        // 登录后自动执行的步骤
+	Script        *Script       `json:"script"`         // 自动化脚本，用于处理交互式提示
+	ProxyCommand  string        `json:"proxy_command"`  // 通过本地命令连接，如 nc -X connect -x proxy:3128 %h %p
+	Proxy         *Proxy        `json:"proxy,omitempty"`
+
+	// 密码来源，连接时解析，用于密码认证或密钥密码，如 cmd:pass show web、env:WEB_PASSWORD、file:~/.secrets/web、keyring:autossh/web
+	PasswordSource string `json:"password_source,omitempty"`
+
+	termWidth     int
+	termHeight    int
+	index         string
+	groupName     string
+	group         *Group
+	globalProxy   *Proxy
+	hostCA        []string
+	proxyDisabled bool // 配置了 "proxy": null，不使用上级代理
+
+	ownOptions    map[string]interface{} // 服务器自身的选项，不含继承的选项
+	optionsMerged bool
+
+	secret         string // 从 password_source 解析的密码
+	secretResolved bool
 }
 
 // 格式化，赋予默认值
@@ -80,18 +104,42 @@ func (server *Server) FormatPrint(flag string, ShowDetail bool) string {
 }
 
 // 生成SSH Client
+// 开启 ControlMaster 选项时优先复用主连接
 func (server *Server) GetSshClient() (*ssh.Client, error) {
+	if server.muxEnabled() {
+		client, err := server.muxClient()
+		if err == nil {
+			return client, nil
+		}
+		utils.Logger.Category("mux").Error("use control master fail ", err)
+	}
+
+	return server.dialSshClient()
+}
+
+// 直接连接服务器
+func (server *Server) dialSshClient() (*ssh.Client, error) {
 	auth, err := parseAuthMethods(server)
 	if err != nil {
 		return nil, err
 	}
 
+	hostKeyCallback, err := server.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
 	config := &ssh.ClientConfig{
-		User: server.User,
-		Auth: auth,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            server.User,
+		Auth:            auth,
+		HostKeyCallback: traceHostKeyCallback(hostKeyCallback),
+		BannerCallback:  traceBannerCallback,
+		Timeout:         server.connectTimeout(),
+	}
+
+	// x/crypto/ssh 只支持 none，无法协商 zlib 压缩
+	if compression, _ := server.Options["Compression"].(bool); compression {
+		utils.Debugln(i18n.T("debug.compression"))
 	}
 
 	// 默认端口为22
@@ -99,46 +147,109 @@ func (server *Server) GetSshClient() (*ssh.Client, error) {
 		server.Port = 22
 	}
 
-	addr := server.Ip + ":" + strconv.Itoa(server.Port)
+	addr := net.JoinHostPort(server.Ip, strconv.Itoa(server.Port))
 
-	if server.group != nil && server.group.Proxy != nil {
-		return server.proxySshClient(server.group.Proxy, addr, config)
-	} else {
-		return ssh.Dial("tcp", addr, config)
+	dialer, err := server.dialer()
+	if err != nil {
+		return nil, err
 	}
+
+	return server.handshake(dialer, addr, config)
 }
 
-func (server *Server) proxySshClient(p *Proxy, sshServerAddr string, sshConfig *ssh.ClientConfig) (client *ssh.Client, err error) {
-	var dialer proxy.Dialer
-	switch p.Type {
-	case ProxyTypeSocks5:
-		var auth proxy.Auth
-		if p.User != "" {
-			auth = proxy.Auth{
-				User:     p.User,
-				Password: p.Password,
-			}
+// 获取连接服务器使用的 Dialer
+func (server *Server) dialer() (proxy.Dialer, error) {
+	command, p, err := server.effectiveProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	if command != "" {
+		return &commandDialer{command: command, user: server.User}, nil
+	}
+
+	direct := &net.Dialer{Timeout: server.connectTimeout()}
+	if p != nil {
+		return newProxyDialer(p, direct)
+	}
+
+	return direct, nil
+}
+
+// 代理配置
+type proxySetting struct {
+	command string
+	proxy   *Proxy
+	direct  bool
+}
+
+// 获取生效的代理配置
+// 按 服务器 > 组 > 上级组 > 全局 的顺序查找，同级的 proxy_command 优先于 proxy，均未配置时直连
+// 服务器配置 "proxy": null 或任意层级配置 {"type": "DIRECT"} 表示直连，配置 {"type": "ENV"} 时读取环境变量
+// 返回的 ProxyCommand 与代理均为空时表示直连
+func (server *Server) effectiveProxy() (string, *Proxy, error) {
+	settings := []proxySetting{{server.ProxyCommand, server.Proxy, server.proxyDisabled}}
+	for group := server.group; group != nil; group = group.parent {
+		settings = append(settings, proxySetting{group.ProxyCommand, group.Proxy, false})
+	}
+	settings = append(settings, proxySetting{"", server.globalProxy, false})
+
+	for _, setting := range settings {
+		if setting.command != "" {
+			return setting.command, nil, nil
 		}
 
-		dialer, err = proxy.SOCKS5("tcp", p.Server+":"+strconv.Itoa(p.Port), &auth, proxy.Direct)
-		if err != nil {
-			return nil, err
+		if setting.direct {
+			return "", nil, nil
+		}
+
+		if setting.proxy != nil {
+			switch ProxyType(strings.ToUpper(string(setting.proxy.Type))) {
+			case ProxyTypeDirect:
+				return "", nil, nil
+			case ProxyTypeEnv:
+				p, err := proxyFromEnvironment(server.Ip)
+				return "", p, err
+			}
+			return "", setting.proxy, nil
 		}
-	default:
-		return nil, errors.New(fmt.Sprintf("unknown proxy type: %s", p.Type))
 	}
 
-	conn, err := dialer.Dial("tcp", sshServerAddr)
-	if err != nil {
-		return nil, err
+	return "", nil, nil
+}
+
+// 解析JSON，记录是否显式配置了 "proxy": null
+func (server *Server) UnmarshalJSON(b []byte) error {
+	type serverAlias Server
+	if err := json.Unmarshal(b, (*serverAlias)(server)); err != nil {
+		return err
 	}
 
-	c, chans, reqs, err := ssh.NewClientConn(conn, sshServerAddr, sshConfig)
-	if err != nil {
-		return nil, err
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	raw, ok := fields["proxy"]
+	server.proxyDisabled = ok && string(bytes.TrimSpace(raw)) == "null"
+
+	return nil
+}
+
+// 生成JSON，保留显式配置的 "proxy": null，选项只保存服务器自身的配置
+func (server *Server) MarshalJSON() ([]byte, error) {
+	type serverAlias Server
+	s := *server
+	if s.optionsMerged {
+		s.Options = s.ownOptions
 	}
 
-	return ssh.NewClient(c, chans, reqs), nil
+	b, err := json.Marshal((*serverAlias)(&s))
+	if err != nil || !server.proxyDisabled || server.Proxy != nil {
+		return b, err
+	}
+
+	return append(b[:len(b)-1], `,"proxy":null}`...), nil
 }
 
 // 生成Sftp Client
@@ -155,8 +266,8 @@ func (server *Server) GetSftpClient() (*sftp.Client, error) {
 func (server *Server) Connect() error {
 	client, err := server.GetSshClient()
 	if err != nil {
-		if utils.ErrorAssert(err, "ssh: unable to authenticate") {
-			return errors.New("连接失败，请检查密码/密钥是否有误")
+		if _, ok := err.(*DialError); ok {
+			return err
 		}
 
 		return errors.New("ssh dial fail:" + err.Error())
@@ -173,18 +284,71 @@ func (server *Server) Connect() error {
 	fd := int(os.Stdin.Fd())
 	oldState, err := terminal.MakeRaw(fd)
 	if err != nil {
-		return errors.New("创建文件描述符出错:" + err.Error())
+		return errors.New(i18n.T("server.fd_failed", err))
 	}
 	defer terminal.Restore(fd, oldState)
 
 	stopKeepAliveLoop := server.startKeepAliveLoop(session)
 	defer close(stopKeepAliveLoop)
 
-	err = server.stdIO(session)
+	sio, err := server.stdIO(session)
 	if err != nil {
 		return err
 	}
+	defer sio.Close()
+
+	if err := server.requestPty(session, fd); err != nil {
+		return err
+	}
+
+	server.listenWindowChange(session, fd)
+
+	if server.RemoteCommand != "" {
+		err = session.Start(server.RemoteCommand)
+	} else {
+		err = session.Shell()
+	}
+	if err != nil {
+		return errors.New(i18n.T("server.shell_failed", err))
+	}
+
+	escape := newEscapeInput(server, client, session, sio, fd, oldState)
+	defer escape.Close()
+
+	// 脚本执行完毕后再交由用户输入
+	go func() {
+		if sio.watcher != nil {
+			server.runScript(sio.stdin, sio.watcher)
+		}
+		escape.copy(os.Stdin)
+	}()
+
+	_ = session.Wait()
+	//if err != nil {
+	//	return errors.New("执行Wait出错:" + err.Error())
+	//}
 
+	return nil
+}
+
+// 会话输入输出
+type sessionIO struct {
+	stdin    io.WriteCloser
+	watcher  *outputWatcher // 需要自动输入时使用，否则为空
+	logger   *sessionLogger
+	received *byteCounter
+	zmodem   *zmodemSession // 未禁用 ZMODEM 时使用，否则为空
+}
+
+// 关闭会话日志
+func (sio *sessionIO) Close() {
+	if sio.logger != nil {
+		_ = sio.logger.Close()
+	}
+}
+
+// 请求远程终端，尺寸与本地终端一致
+func (server *Server) requestPty(session *ssh.Session, fd int) error {
 	modes := ssh.TerminalModes{
 		ssh.ECHO:          1,
 		ssh.TTY_OP_ISPEED: 14400,
@@ -197,68 +361,50 @@ func (server *Server) Connect() error {
 		termType = "xterm-256color"
 	}
 	if err := session.RequestPty(termType, server.termHeight, server.termWidth, modes); err != nil {
-		return errors.New("创建终端出错:" + err.Error())
+		return errors.New(i18n.T("server.pty_failed", err))
 	}
 
-	server.listenWindowChange(session, fd)
-
-	err = session.Shell()
-	if err != nil {
-		return errors.New("执行Shell出错:" + err.Error())
-	}
-
-	_ = session.Wait()
-	//if err != nil {
-	//	return errors.New("执行Wait出错:" + err.Error())
-	//}
-
 	return nil
 }
 
 // 重定向标准输入输出
-func (server *Server) stdIO(session *ssh.Session) error {
+func (server *Server) stdIO(session *ssh.Session) (*sessionIO, error) {
+	sio := &sessionIO{received: new(byteCounter)}
 	session.Stderr = os.Stderr
-	session.Stdin = os.Stdin
+	writers := []io.Writer{os.Stdout}
 
 	if server.Log.Enable {
-		ch, err := session.StdoutPipe()
+		logger, err := newSessionLogger(server)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		sio.logger = logger
+		writers = append(writers, logger)
+	}
 
-		go func() {
-			flag := os.O_RDWR | os.O_CREATE
-			switch server.Log.Mode {
-			case LogModeAppend:
-				flag = flag | os.O_APPEND
-			case LogModeCover:
-			}
-
-			f, err := os.OpenFile(server.formatLogFilename(server.Log.Filename), flag, 0644)
-			if err != nil {
-				utils.Logger.Error("Open file fail ", err)
-				return
-			}
+	// 用户输入经转义处理后写入
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		sio.Close()
+		return nil, err
+	}
+	sio.stdin = stdin
 
-			for {
-				buff := [4096]byte{}
-				n, _ := ch.Read(buff[:])
-				if n > 0 {
-					if _, err := f.Write(buff[:n]); err != nil {
-						utils.Logger.Error("Write file buffer fail ", err)
-					}
+	if len(server.Startup) > 0 || server.Script != nil {
+		sio.watcher = newOutputWatcher()
+		writers = append(writers, sio.watcher)
+	}
 
-					if _, err := os.Stdout.Write(buff[:n]); err != nil {
-						utils.Logger.Error("Write stdout buffer fail ", err)
-					}
-				}
-			}
-		}()
-	} else {
-		session.Stdout = os.Stdout
+	// ZMODEM 传输的内容不写入终端及日志，可通过 Zmodem 选项禁用
+	var output io.Writer = io.MultiWriter(writers...)
+	if enabled, ok := server.Options["Zmodem"].(bool); !ok || enabled {
+		sio.zmodem = newZmodemSession(server, output, stdin)
+		output = sio.zmodem
 	}
 
-	return nil
+	session.Stdout = io.MultiWriter(sio.received, output)
+
+	return sio, nil
 }
 
 // 格式化日志文件名
@@ -270,6 +416,8 @@ func (server *Server) formatLogFilename(filename string) string {
 		{"%d": time.Now().Format("20060102")},
 		{"%u": server.User},
 		{"%a": server.Alias},
+		{"%i": server.Ip},
+		{"%p": strconv.Itoa(os.Getpid())},
 	}
 
 	for _, kv := range kvs {
@@ -285,13 +433,18 @@ func (server *Server) formatLogFilename(filename string) string {
 func parseAuthMethods(server *Server) ([]ssh.AuthMethod, error) {
 	var sshs []ssh.AuthMethod
 
+	password, err := server.password()
+	if err != nil {
+		return nil, err
+	}
+
 	switch strings.ToLower(server.Method) {
 	case "password":
-		sshs = append(sshs, ssh.Password(server.Password))
+		sshs = append(sshs, passwordMethod(password))
 		break
 
 	case "key":
-		method, err := pemKey(server)
+		method, err := pemKey(server, password)
 		if err != nil {
 			return nil, err
 		}
@@ -300,18 +453,35 @@ func parseAuthMethods(server *Server) ([]ssh.AuthMethod, error) {
 
 		// 默认以password方式
 	default:
-		sshs = append(sshs, ssh.Password(server.Password))
+		sshs = append(sshs, passwordMethod(password))
 	}
 
 	return sshs, nil
 }
 
-// 解析密钥
-func pemKey(server *Server) (ssh.AuthMethod, error) {
+// 密码认证
+func passwordMethod(password string) ssh.AuthMethod {
+	return ssh.PasswordCallback(func() (string, error) {
+		utils.Debugln(i18n.T("debug.try_password"))
+		return password, nil
+	})
+}
+
+// 未指定密钥时使用的默认密钥
+const defaultServerKey = "~/.ssh/id_rsa"
+
+// 密钥路径，未指定时为默认密钥
+func (server *Server) keyFile() string {
 	if server.Key == "" {
-		server.Key = "~/.ssh/id_rsa"
+		return defaultServerKey
 	}
-	server.Key, _ = utils.ParsePath(server.Key)
+
+	return server.Key
+}
+
+// 解析密钥，passphrase 不为空时作为密钥密码
+func pemKey(server *Server, passphrase string) (ssh.AuthMethod, error) {
+	server.Key, _ = utils.ParsePath(server.keyFile())
 
 	pemBytes, err := ioutil.ReadFile(server.Key)
 	if err != nil {
@@ -319,17 +489,24 @@ func pemKey(server *Server) (ssh.AuthMethod, error) {
 	}
 
 	var signer ssh.Signer
-	if server.Password == "" {
+	if passphrase == "" {
 		signer, err = ssh.ParsePrivateKey(pemBytes)
 	} else {
-		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(server.Password))
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	return ssh.PublicKeys(signer), nil
+	if signer, err = server.certSigner(signer); err != nil {
+		return nil, err
+	}
+
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		utils.Debugln(i18n.T("debug.try_publickey"), server.Key, ssh.FingerprintSHA256(signer.PublicKey()))
+		return []ssh.Signer{signer}, nil
+	}), nil
 }
 
 // 发送心跳包
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bufio"
+	"bytes"
+	"errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+)
+
+// 证书文件的默认后缀，与 OpenSSH 一致，如 id_ed25519-cert.pub
+const certSuffix = "-cert.pub"
+
+// 加载用户证书，未配置 cert 时尝试加载 密钥路径-cert.pub
+// 显式配置的证书加载失败或已过期时返回错误，自动加载的证书不可用时仅提示并使用密钥认证
+func (server *Server) certSigner(signer ssh.Signer) (ssh.Signer, error) {
+	file, explicit := server.Cert, server.Cert != ""
+	if !explicit {
+		file = server.Key + certSuffix
+		if exists, _ := utils.FileIsExists(file); !exists {
+			return signer, nil
+		}
+	}
+
+	certSigner, err := loadCertSigner(file, signer, time.Now())
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+
+		utils.Errorln(err)
+		return signer, nil
+	}
+
+	return certSigner, nil
+}
+
+// 读取证书并与私钥组合
+func loadCertSigner(file string, signer ssh.Signer, now time.Time) (ssh.Signer, error) {
+	file, err := utils.ParsePath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return nil, errors.New(i18n.T("cert.invalid", file, err))
+	}
+
+	cert, ok := key.(*ssh.Certificate)
+	if !ok || cert.CertType != ssh.UserCert {
+		return nil, errors.New(i18n.T("cert.not_user_cert", file))
+	}
+
+	if err := checkCertValidity(cert, file, now); err != nil {
+		return nil, err
+	}
+
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, errors.New(i18n.T("cert.key_mismatch", file))
+	}
+
+	utils.Debugln(i18n.T("debug.cert", file, cert.KeyId, strings.Join(cert.ValidPrincipals, ",")))
+	return certSigner, nil
+}
+
+// 检查证书有效期，name 用于提示
+func checkCertValidity(cert *ssh.Certificate, name string, now time.Time) error {
+	unixNow := now.Unix()
+	if unixNow < int64(cert.ValidAfter) {
+		return errors.New(i18n.T("cert.not_yet_valid", name, formatCertTime(cert.ValidAfter)))
+	}
+
+	if cert.ValidBefore != ssh.CertTimeInfinity && unixNow >= int64(cert.ValidBefore) {
+		return errors.New(i18n.T("cert.expired", name, formatCertTime(cert.ValidBefore)))
+	}
+
+	return nil
+}
+
+func formatCertTime(t uint64) string {
+	return time.Unix(int64(t), 0).Format("2006-01-02 15:04:05")
+}
+
+// 解析主机证书的签发机构，每项可为公钥或公钥文件路径
+func parseHostCAs(items []string) ([]ssh.PublicKey, error) {
+	keys := make([]ssh.PublicKey, 0)
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(item)); err == nil {
+			keys = append(keys, key)
+			continue
+		}
+
+		file, err := utils.ParsePath(item)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.New(i18n.T("cert.invalid_ca", item))
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+			if err != nil {
+				return nil, errors.New(i18n.T("cert.invalid_ca", item))
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// 主机密钥校验，配置了 host_ca 时要求 CA 签发的主机证书，非证书的主机密钥需在 known_hosts 中
+// 主机密钥算法使用 x/crypto/ssh 的默认列表，默认已优先协商证书
+func (server *Server) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	cas, err := parseHostCAs(server.hostCA)
+	if err != nil {
+		return nil, err
+	}
+	if len(cas) == 0 {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		}, nil
+	}
+
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			for _, ca := range cas {
+				if bytes.Equal(auth.Marshal(), ca.Marshal()) {
+					return true
+				}
+			}
+			return false
+		},
+		HostKeyFallback: server.knownHostsCallback(),
+	}
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if cert, ok := key.(*ssh.Certificate); ok {
+			if err := checkCertValidity(cert, i18n.T("cert.host_name", hostname), time.Now()); err != nil {
+				return err
+			}
+		}
+
+		return checker.CheckHostKey(hostname, remote, key)
+	}
+
+	return callback, nil
+}
+
+// 按 known_hosts 校验非证书的主机密钥，可通过 UserKnownHostsFile 选项指定文件，默认为 ~/.ssh/known_hosts
+func (server *Server) knownHostsCallback() ssh.HostKeyCallback {
+	file, _ := server.Options["UserKnownHostsFile"].(string)
+	if file == "" {
+		file = "~/.ssh/known_hosts"
+	}
+
+	callback, err := func() (ssh.HostKeyCallback, error) {
+		path, err := utils.ParsePath(file)
+		if err != nil {
+			return nil, err
+		}
+		return knownhosts.New(path)
+	}()
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err == nil {
+			err = callback(hostname, remote, key)
+		}
+		if err != nil {
+			return errors.New(i18n.T("cert.host_untrusted", hostname, file, err))
+		}
+		return nil
+	}
+}
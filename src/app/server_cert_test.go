@@ -0,0 +1,144 @@
+package app
+
+import (
+	"crypto/rand"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func newTestCert(t *testing.T, ca ssh.Signer, key ssh.PublicKey, certType uint32, validBefore time.Time) *ssh.Certificate {
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        certType,
+		KeyId:           "test",
+		ValidPrincipals: []string{"127.0.0.1"},
+		ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestLoadCertSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autossh-cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestSigner(t)
+	signer := newTestSigner(t)
+	file := filepath.Join(dir, "id_ed25519"+certSuffix)
+	writeCert := func(cert *ssh.Certificate) {
+		if err := ioutil.WriteFile(file, ssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeCert(newTestCert(t, ca, signer.PublicKey(), ssh.UserCert, time.Now().Add(time.Hour)))
+	certSigner, err := loadCertSigner(file, signer, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certSigner.PublicKey().Type() != ssh.CertAlgoED25519v01 {
+		t.Errorf("type = %s", certSigner.PublicKey().Type())
+	}
+
+	if _, err := loadCertSigner(file, newTestSigner(t), time.Now()); err == nil {
+		t.Error("expected error for mismatched key")
+	}
+	if _, err := loadCertSigner(file, signer, time.Now().Add(2*time.Hour)); err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("expected expired error, got %v", err)
+	}
+
+	writeCert(newTestCert(t, ca, signer.PublicKey(), ssh.HostCert, time.Now().Add(time.Hour)))
+	if _, err := loadCertSigner(file, signer, time.Now()); err == nil {
+		t.Error("expected error for host certificate")
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	ca := newTestSigner(t)
+	host := newTestSigner(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+
+	dir, err := ioutil.TempDir("", "autossh-known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	server := &Server{
+		hostCA:  []string{string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+		Options: map[string]interface{}{"UserKnownHostsFile": knownHosts},
+	}
+	callback, err := server.hostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := newTestCert(t, ca, host.PublicKey(), ssh.HostCert, time.Now().Add(time.Hour))
+	if err := callback("127.0.0.1:22", remote, valid); err != nil {
+		t.Errorf("valid host cert: %v", err)
+	}
+
+	// 配置了 host_ca 后，非证书的主机密钥需在 known_hosts 中
+	if err := callback("127.0.0.1:22", remote, host.PublicKey()); err == nil {
+		t.Error("expected error for plain host key without known_hosts")
+	}
+	line := knownhosts.Line([]string{knownhosts.Normalize("127.0.0.1:22")}, host.PublicKey())
+	if err := ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if callback, err = server.hostKeyCallback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("127.0.0.1:22", remote, host.PublicKey()); err != nil {
+		t.Errorf("plain host key in known_hosts: %v", err)
+	}
+	if err := callback("127.0.0.1:22", remote, newTestSigner(t).PublicKey()); err == nil {
+		t.Error("expected error for changed host key")
+	}
+
+	untrusted := newTestCert(t, newTestSigner(t), host.PublicKey(), ssh.HostCert, time.Now().Add(time.Hour))
+	if err := callback("127.0.0.1:22", remote, untrusted); err == nil {
+		t.Error("expected error for untrusted CA")
+	}
+
+	expired := newTestCert(t, ca, host.PublicKey(), ssh.HostCert, time.Now().Add(-time.Minute))
+	if err := callback("127.0.0.1:22", remote, expired); err == nil {
+		t.Error("expected error for expired host cert")
+	}
+
+	// 未配置 host_ca 时不校验
+	callback, err = (&Server{}).hostKeyCallback()
+	if err != nil || callback("127.0.0.1:22", remote, untrusted) != nil {
+		t.Error("expected accept-all callback without host_ca")
+	}
+}
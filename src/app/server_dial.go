@@ -0,0 +1,234 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultConnectTimeout   = 10 // 建立连接超时秒数
+	defaultHandshakeTimeout = 30 // SSH握手及认证超时秒数
+)
+
+type DialErrorKind string
+
+const (
+	DialErrorDns     DialErrorKind = "dns"
+	DialErrorRefused DialErrorKind = "refused"
+	DialErrorTimeout DialErrorKind = "timeout"
+	DialErrorAuth    DialErrorKind = "auth"
+	DialErrorHostKey DialErrorKind = "hostkey"
+	DialErrorProxy   DialErrorKind = "proxy"
+	DialErrorUnknown DialErrorKind = "unknown"
+)
+
+// 连接失败的错误
+type DialError struct {
+	Kind    DialErrorKind
+	Addr    string
+	Methods []string // 认证失败时已尝试的认证方式
+	Cause   string   // 代理错误的具体原因
+	Err     error
+}
+
+func (e *DialError) Error() string {
+	switch e.Kind {
+	case DialErrorDns:
+		return i18n.T("dial.dns", e.Addr, e.Err)
+	case DialErrorRefused:
+		return i18n.T("dial.refused", e.Addr)
+	case DialErrorTimeout:
+		return i18n.T("dial.timeout", e.Addr)
+	case DialErrorAuth:
+		return i18n.T("dial.auth", strings.Join(e.Methods, ", "))
+	case DialErrorHostKey:
+		return i18n.T("dial.hostkey", e.Err)
+	case DialErrorProxy:
+		return i18n.T("dial.proxy", e.Cause, e.Err)
+	default:
+		return "ssh dial fail:" + e.Err.Error()
+	}
+}
+
+// 主机密钥校验错误，握手失败时用于识别错误类型
+type hostKeyError struct {
+	err error
+}
+
+const hostKeyErrorPrefix = "host key verification failed: "
+
+func (e *hostKeyError) Error() string {
+	return hostKeyErrorPrefix + e.err.Error()
+}
+
+// 连接超时，对应选项 ConnectTimeout（秒）
+func (server *Server) connectTimeout() time.Duration {
+	return server.optionSeconds("ConnectTimeout", defaultConnectTimeout)
+}
+
+// 握手超时，对应选项 HandshakeTimeout（秒）
+func (server *Server) handshakeTimeout() time.Duration {
+	return server.optionSeconds("HandshakeTimeout", defaultHandshakeTimeout)
+}
+
+func (server *Server) optionSeconds(name string, deft int) time.Duration {
+	if val, ok := server.Options[name].(float64); ok && val > 0 {
+		return time.Duration(val * float64(time.Second))
+	}
+
+	return time.Duration(deft) * time.Second
+}
+
+// 跟踪主机密钥校验过程，并将校验失败包装为 hostKeyError
+func traceHostKeyCallback(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		utils.Debugln(i18n.T("debug.host_key"), key.Type(), ssh.FingerprintSHA256(key))
+		if err := callback(hostname, remote, key); err != nil {
+			return &hostKeyError{err: err}
+		}
+
+		return nil
+	}
+}
+
+func traceBannerCallback(message string) error {
+	utils.Debugln(i18n.T("debug.banner"), strings.TrimSpace(message))
+	return nil
+}
+
+// 连接地址并完成SSH握手
+func (server *Server) handshake(dialer proxy.Dialer, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	_, isDirect := dialer.(*net.Dialer)
+
+	startTime := time.Now()
+	utils.Debugln(i18n.T("debug.connecting", addr, server.connectTimeout(), describeDialer(dialer)))
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, classifyDialError(addr, err, !isDirect)
+	}
+	utils.Debugln(i18n.T("debug.connected", conn.RemoteAddr(), time.Now().Sub(startTime)))
+
+	deadline := time.Now().Add(server.handshakeTimeout())
+	_ = conn.SetDeadline(deadline)
+
+	utils.Debugln(i18n.T("debug.handshake", config.User, server.handshakeTimeout()))
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, classifyHandshakeError(addr, err, !time.Now().Before(deadline), !isDirect)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	utils.Debugln(i18n.T("debug.authenticated", string(c.ServerVersion()), time.Now().Sub(startTime)))
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func describeDialer(dialer proxy.Dialer) string {
+	switch d := dialer.(type) {
+	case *net.Dialer:
+		return i18n.T("dialer.direct")
+	case *commandDialer:
+		return i18n.T("dialer.command", d.command)
+	default:
+		return i18n.T("dialer.proxy")
+	}
+}
+
+// 识别建立连接阶段的错误
+func classifyDialError(addr string, err error, viaProxy bool) error {
+	kind := classifyNetError(err)
+	if viaProxy {
+		return &DialError{Kind: DialErrorProxy, Addr: addr, Cause: describeKind(kind), Err: err}
+	}
+
+	return &DialError{Kind: kind, Addr: addr, Err: err}
+}
+
+// 识别网络错误类型
+func classifyNetError(err error) DialErrorKind {
+	for err != nil {
+		switch e := err.(type) {
+		case *net.DNSError:
+			return DialErrorDns
+		case *net.OpError:
+			if e.Timeout() {
+				return DialErrorTimeout
+			}
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case syscall.Errno:
+			if e == syscall.ECONNREFUSED {
+				return DialErrorRefused
+			}
+			if e == syscall.ETIMEDOUT {
+				return DialErrorTimeout
+			}
+			return DialErrorUnknown
+		case net.Error:
+			if e.Timeout() {
+				return DialErrorTimeout
+			}
+			return DialErrorUnknown
+		default:
+			return DialErrorUnknown
+		}
+	}
+
+	return DialErrorUnknown
+}
+
+func describeKind(kind DialErrorKind) string {
+	switch kind {
+	case DialErrorDns:
+		return i18n.T("cause.dns")
+	case DialErrorRefused:
+		return i18n.T("cause.refused")
+	case DialErrorTimeout:
+		return i18n.T("cause.timeout")
+	default:
+		return i18n.T("cause.unknown")
+	}
+}
+
+var attemptedMethodsRegexp = regexp.MustCompile(`attempted methods \[([^\]]*)\]`)
+
+// 识别握手阶段的错误
+func classifyHandshakeError(addr string, err error, timeout bool, viaProxy bool) error {
+	msg := err.Error()
+
+	if strings.Contains(msg, hostKeyErrorPrefix) {
+		return &DialError{Kind: DialErrorHostKey, Addr: addr, Err: errors.New(msg[strings.Index(msg, hostKeyErrorPrefix)+len(hostKeyErrorPrefix):])}
+	}
+
+	if matches := attemptedMethodsRegexp.FindStringSubmatch(msg); matches != nil {
+		methods := make([]string, 0)
+		for _, method := range strings.Fields(matches[1]) {
+			if method != "none" {
+				methods = append(methods, method)
+			}
+		}
+		return &DialError{Kind: DialErrorAuth, Addr: addr, Methods: methods, Err: err}
+	}
+
+	if timeout {
+		return &DialError{Kind: DialErrorTimeout, Addr: addr, Err: err}
+	}
+
+	// 通过代理连接时，握手阶段连接被关闭通常是代理无法连接目标地址
+	if viaProxy && (err == io.EOF || strings.HasSuffix(msg, io.EOF.Error())) {
+		return &DialError{Kind: DialErrorProxy, Addr: addr, Cause: i18n.T("cause.closed"), Err: err}
+	}
+
+	return &DialError{Kind: DialErrorUnknown, Addr: addr, Err: err}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDialErrorKind(t *testing.T) {
+	sshListener := startTestSshServer(t, "secret")
+	defer sshListener.Close()
+	sshPort := sshListener.Addr().(*net.TCPAddr).Port
+
+	// 获取一个未被监听的端口
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	_ = closed.Close()
+
+	cases := []struct {
+		name   string
+		server *Server
+		kind   DialErrorKind
+	}{
+		{"refused", &Server{Ip: "127.0.0.1", Port: closedPort, User: "test", Password: "secret"}, DialErrorRefused},
+		{"auth", &Server{Ip: "127.0.0.1", Port: sshPort, User: "test", Password: "wrong"}, DialErrorAuth},
+		{"proxy", &Server{Ip: "127.0.0.1", Port: sshPort, User: "test", Password: "secret",
+			Proxy: &Proxy{Type: ProxyTypeSocks5, Server: "127.0.0.1", Port: closedPort}}, DialErrorProxy},
+	}
+
+	for _, c := range cases {
+		_, err := c.server.dialSshClient()
+		dialErr, ok := err.(*DialError)
+		if !ok {
+			t.Errorf("%s: got %v, want *DialError", c.name, err)
+			continue
+		}
+		if dialErr.Kind != c.kind {
+			t.Errorf("%s: kind = %s, want %s (%v)", c.name, dialErr.Kind, c.kind, err)
+		}
+	}
+}
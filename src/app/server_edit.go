@@ -10,7 +10,7 @@ import (
 
 // 编辑
 func (server *Server) Edit() error {
-	keys := []string{"Name", "Ip", "Port", "User", "Password", "Method", "Key", "Alias"}
+	keys := []string{"Name", "Ip", "Port", "User", "Password", "PasswordSource", "Method", "Key", "Alias"}
 	for _, key := range keys {
 		if err := server.scanVal(key); err != nil {
 			return err
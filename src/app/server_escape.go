@@ -0,0 +1,461 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认转义字符，与 OpenSSH 一致，可通过 EscapeChar 选项修改，设置为 none 时禁用
+const defaultEscapeChar = '~'
+
+// 交互会话的转义序列，仅在行首输入转义字符时生效
+// ~. 断开连接 ~B 发送 break ~C 命令行 ~s ~# 连接信息 ~? 帮助 ~~ 输入 ~ 本身
+type escapeInput struct {
+	server   *Server
+	client   *ssh.Client
+	session  *ssh.Session
+	stdin    io.Writer
+	out      io.Writer
+	fd       int
+	oldState *terminal.State // 登录前的终端状态，进入命令行时恢复
+
+	char      byte
+	enabled   bool
+	lineStart bool
+	pending   bool
+
+	started  time.Time
+	sent     int64
+	received *byteCounter
+	zmodem   *zmodemSession
+
+	mu       sync.Mutex
+	forwards map[string]*localForward
+}
+
+func newEscapeInput(server *Server, client *ssh.Client, session *ssh.Session, sio *sessionIO, fd int, oldState *terminal.State) *escapeInput {
+	escape := &escapeInput{
+		server:    server,
+		client:    client,
+		session:   session,
+		stdin:     sio.stdin,
+		out:       os.Stdout,
+		fd:        fd,
+		oldState:  oldState,
+		char:      defaultEscapeChar,
+		enabled:   true,
+		lineStart: true,
+		started:   time.Now(),
+		received:  sio.received,
+		zmodem:    sio.zmodem,
+		forwards:  make(map[string]*localForward),
+	}
+
+	if val, ok := server.Options["EscapeChar"].(string); ok && val != "" {
+		if strings.ToLower(val) == "none" {
+			escape.enabled = false
+		} else {
+			escape.char = val[0]
+		}
+	}
+
+	return escape
+}
+
+// 处理一个输入字节，返回需要发送到服务器的内容及转义命令
+func (escape *escapeInput) feed(c byte) ([]byte, byte) {
+	if escape.pending {
+		escape.pending = false
+		switch c {
+		case escape.char:
+			escape.lineStart = false
+			return []byte{c}, 0
+		case '.', 'B', 'C', 's', '#', '?':
+			return nil, c
+		default:
+			escape.lineStart = c == '\r' || c == '\n'
+			return []byte{escape.char, c}, 0
+		}
+	}
+
+	if escape.enabled && escape.lineStart && c == escape.char {
+		escape.pending = true
+		return nil, 0
+	}
+
+	escape.lineStart = c == '\r' || c == '\n'
+	return []byte{c}, 0
+}
+
+// 读取用户输入并发送到服务器，断开连接或输入结束时返回
+func (escape *escapeInput) copy(r io.Reader) {
+	reader := bufio.NewReader(r)
+	buff := make([]byte, 0, 1024)
+
+	flush := func() bool {
+		if len(buff) == 0 {
+			return true
+		}
+		n, err := escape.stdin.Write(buff)
+		atomic.AddInt64(&escape.sent, int64(n))
+		buff = buff[:0]
+		return err == nil
+	}
+
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			flush()
+			return
+		}
+
+		// ZMODEM 传输中的输入不发送到服务器
+		if escape.zmodem != nil && escape.zmodem.input(c) {
+			continue
+		}
+
+		data, cmd := escape.feed(c)
+		buff = append(buff, data...)
+
+		if cmd != 0 {
+			if !flush() {
+				return
+			}
+			if !escape.handle(cmd, reader) {
+				return
+			}
+			continue
+		}
+
+		if reader.Buffered() == 0 || len(buff) >= cap(buff) {
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// 执行转义命令，返回 false 时停止读取输入
+func (escape *escapeInput) handle(cmd byte, reader *bufio.Reader) bool {
+	switch cmd {
+	case '.':
+		escape.println(i18n.T("escape.disconnected", escape.server.Name))
+		// 与 OpenSSH 一致直接关闭连接，连接已中断时服务器不会响应会话的关闭
+		_ = escape.client.Close()
+		return false
+	case 'B':
+		// RFC 4335，break 时长为毫秒
+		payload := ssh.Marshal(struct{ Length uint32 }{1000})
+		if _, err := escape.session.SendRequest("break", false, payload); err != nil {
+			escape.println(err.Error())
+		}
+	case 's', '#':
+		escape.printStats()
+	case '?':
+		escape.println(strings.Replace(i18n.T("escape.help", string(escape.char)), "\n", "\r\n", -1))
+	case 'C':
+		escape.commandLine(reader)
+	}
+
+	return true
+}
+
+// 输出提示信息，终端处于 raw 模式，需使用 \r\n 换行
+func (escape *escapeInput) println(msg string) {
+	_, _ = fmt.Fprint(escape.out, "\r\n"+msg+"\r\n")
+}
+
+func (escape *escapeInput) printStats() {
+	lines := []string{
+		i18n.T("escape.stats_server", escape.server.Name, escape.client.RemoteAddr()),
+		i18n.T("escape.stats_duration", time.Since(escape.started).Round(time.Second)),
+		i18n.T("escape.stats_bytes", atomic.LoadInt64(&escape.sent), escape.received.count()),
+	}
+
+	escape.mu.Lock()
+	for _, forward := range escape.sortedForwards() {
+		lines = append(lines, i18n.T("escape.stats_forward", forward.listen, forward.target))
+	}
+	escape.mu.Unlock()
+
+	escape.println(strings.Join(lines, "\r\n"))
+}
+
+// 命令行模式，恢复终端后读取一行命令，执行完毕后重新进入 raw 模式
+func (escape *escapeInput) commandLine(reader *bufio.Reader) {
+	// 恢复终端后 Ctrl+C 会产生 SIGINT，输入期间接管该信号以免结束程序，Ctrl+C 清空当前行，空行取消
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	_ = terminal.Restore(escape.fd, escape.oldState)
+	defer func() {
+		_, _ = terminal.MakeRaw(escape.fd)
+	}()
+
+	_, _ = fmt.Fprint(escape.out, "\r\nssh> ")
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+
+	if err := escape.runCommand(strings.Fields(line)); err != nil {
+		_, _ = fmt.Fprintln(escape.out, err)
+	}
+}
+
+// 执行命令行中的命令
+// -L [bind:]port:host:hostport 添加端口转发
+// -KL [bind:]port 取消端口转发
+// cp [-r] source target 复制文件，:path 表示当前服务器上的路径
+func (escape *escapeInput) runCommand(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch {
+	case args[0] == "cp":
+		return escape.cp(args[1:])
+	case strings.HasPrefix(args[0], "-L"), strings.HasPrefix(args[0], "-KL"):
+		option := "-L"
+		if strings.HasPrefix(args[0], "-KL") {
+			option = "-KL"
+		}
+
+		spec := strings.TrimPrefix(args[0], option)
+		if spec == "" && len(args) > 1 {
+			spec = args[1]
+		}
+		if spec == "" {
+			return errors.New(i18n.T("escape.invalid_command", strings.Join(args, " ")))
+		}
+
+		if option == "-KL" {
+			return escape.cancelForward(spec)
+		}
+		return escape.addForward(spec)
+	default:
+		return errors.New(i18n.T("escape.invalid_command", strings.Join(args, " ")))
+	}
+}
+
+// 在当前连接上复制文件
+func (escape *escapeInput) cp(args []string) error {
+	sftpClient, err := sftp.NewClient(escape.client)
+	if err != nil {
+		return err
+	}
+
+	cp := Cp{
+		sftpClients: map[*Server]*sftp.Client{escape.server: sftpClient},
+		sshClients:  map[*Server]*ssh.Client{escape.server: escape.client},
+	}
+	err = cp.parseWith(args, func(raw string) (*TransferObject, error) {
+		obj := &TransferObject{raw: raw, resType: ResTypeSrc, path: raw}
+		if strings.HasPrefix(raw, ":") {
+			obj.resType = ResTypeDst
+			obj.server = escape.server
+			obj.path = raw[1:]
+		}
+		return obj, nil
+	})
+	if err != nil {
+		_ = sftpClient.Close()
+		return err
+	}
+
+	cp.run()
+	return nil
+}
+
+// 本地端口转发
+type localForward struct {
+	listen   string
+	target   string
+	listener net.Listener
+}
+
+// 解析 [bind:]port:host:hostport，bind 及 host 可使用 [ipv6] 形式
+func parseForwardSpec(spec string) (string, string, error) {
+	parts := splitForwardSpec(spec)
+	switch len(parts) {
+	case 3:
+		return net.JoinHostPort("localhost", parts[0]), net.JoinHostPort(parts[1], parts[2]), nil
+	case 4:
+		bind := parts[0]
+		if bind == "*" {
+			bind = ""
+		}
+		return net.JoinHostPort(bind, parts[1]), net.JoinHostPort(parts[2], parts[3]), nil
+	default:
+		return "", "", errors.New(i18n.T("escape.invalid_forward", spec))
+	}
+}
+
+// 解析 [bind:]port，用于取消转发
+func parseForwardListen(spec string) (string, error) {
+	parts := splitForwardSpec(spec)
+	switch len(parts) {
+	case 1:
+		return net.JoinHostPort("localhost", parts[0]), nil
+	case 2:
+		bind := parts[0]
+		if bind == "*" {
+			bind = ""
+		}
+		return net.JoinHostPort(bind, parts[1]), nil
+	default:
+		return "", errors.New(i18n.T("escape.invalid_forward", spec))
+	}
+}
+
+// 按冒号拆分，方括号内的冒号不拆分
+func splitForwardSpec(spec string) []string {
+	parts := make([]string, 0)
+	var current strings.Builder
+	inBracket := false
+	for _, c := range spec {
+		switch {
+		case c == '[' && !inBracket:
+			inBracket = true
+		case c == ']' && inBracket:
+			inBracket = false
+		case c == ':' && !inBracket:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+
+	return append(parts, current.String())
+}
+
+func (escape *escapeInput) addForward(spec string) error {
+	listen, target, err := parseForwardSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	escape.mu.Lock()
+	defer escape.mu.Unlock()
+
+	if _, ok := escape.forwards[listen]; ok {
+		return errors.New(i18n.T("escape.forward_exists", listen))
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+
+	forward := &localForward{listen: listen, target: target, listener: listener}
+	escape.forwards[listen] = forward
+	go escape.serveForward(forward)
+
+	_, _ = fmt.Fprintln(escape.out, i18n.T("escape.forward_added", listen, target))
+	return nil
+}
+
+func (escape *escapeInput) cancelForward(spec string) error {
+	listen, err := parseForwardListen(spec)
+	if err != nil {
+		return err
+	}
+
+	escape.mu.Lock()
+	defer escape.mu.Unlock()
+
+	forward, ok := escape.forwards[listen]
+	if !ok {
+		return errors.New(i18n.T("escape.forward_not_found", listen))
+	}
+
+	delete(escape.forwards, listen)
+	_ = forward.listener.Close()
+
+	_, _ = fmt.Fprintln(escape.out, i18n.T("escape.forward_canceled", listen))
+	return nil
+}
+
+func (escape *escapeInput) serveForward(forward *localForward) {
+	for {
+		conn, err := forward.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			remote, err := escape.client.Dial("tcp", forward.target)
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(remote, conn)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(conn, remote)
+				done <- struct{}{}
+			}()
+			<-done
+		}(conn)
+	}
+}
+
+// 按监听地址排序，调用方需持有锁
+func (escape *escapeInput) sortedForwards() []*localForward {
+	forwards := make([]*localForward, 0, len(escape.forwards))
+	for _, forward := range escape.forwards {
+		forwards = append(forwards, forward)
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		return forwards[i].listen < forwards[j].listen
+	})
+
+	return forwards
+}
+
+// 关闭所有端口转发
+func (escape *escapeInput) Close() {
+	escape.mu.Lock()
+	defer escape.mu.Unlock()
+
+	for listen, forward := range escape.forwards {
+		_ = forward.listener.Close()
+		delete(escape.forwards, listen)
+	}
+}
+
+// 统计字节数
+type byteCounter struct {
+	n int64
+}
+
+func (counter *byteCounter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&counter.n, int64(len(p)))
+	return len(p), nil
+}
+
+func (counter *byteCounter) count() int64 {
+	return atomic.LoadInt64(&counter.n)
+}
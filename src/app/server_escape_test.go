@@ -0,0 +1,173 @@
+package app
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEscapeFeed(t *testing.T) {
+	cases := []struct {
+		input string
+		sent  string
+		cmds  string
+	}{
+		{"ls\r", "ls\r", ""},
+		{"~.", "", "."},
+		{"ls\r~?~.", "ls\r", "?."},
+		{"a~.", "a~.", ""},
+		{"~~.", "~.", ""},
+		{"~x", "~x", ""},
+		{"~\r~s", "~\r", "s"},
+		{"\n~C", "\n", "C"},
+	}
+
+	for _, c := range cases {
+		escape := &escapeInput{char: defaultEscapeChar, enabled: true, lineStart: true}
+		var sent, cmds []byte
+		for i := 0; i < len(c.input); i++ {
+			data, cmd := escape.feed(c.input[i])
+			sent = append(sent, data...)
+			if cmd != 0 {
+				cmds = append(cmds, cmd)
+			}
+		}
+
+		if string(sent) != c.sent || string(cmds) != c.cmds {
+			t.Errorf("feed(%q) = %q %q, want %q %q", c.input, sent, cmds, c.sent, c.cmds)
+		}
+	}
+
+	// 禁用转义字符
+	escape := &escapeInput{char: defaultEscapeChar, lineStart: true}
+	if data, cmd := escape.feed('~'); string(data) != "~" || cmd != 0 {
+		t.Errorf("disabled feed = %q %q", data, cmd)
+	}
+}
+
+func TestParseForwardSpec(t *testing.T) {
+	cases := []struct {
+		spec   string
+		listen string
+		target string
+	}{
+		{"8080:localhost:80", "localhost:8080", "localhost:80"},
+		{"0.0.0.0:8080:10.0.0.2:80", "0.0.0.0:8080", "10.0.0.2:80"},
+		{"*:8080:db:5432", ":8080", "db:5432"},
+		{"[::1]:8080:[fe80::1]:22", "[::1]:8080", "[fe80::1]:22"},
+	}
+
+	for _, c := range cases {
+		listen, target, err := parseForwardSpec(c.spec)
+		if err != nil {
+			t.Errorf("parseForwardSpec(%q) error: %v", c.spec, err)
+			continue
+		}
+		if listen != c.listen || target != c.target {
+			t.Errorf("parseForwardSpec(%q) = %s %s, want %s %s", c.spec, listen, target, c.listen, c.target)
+		}
+	}
+
+	for _, spec := range []string{"8080", "8080:80", "a:b:c:d:e"} {
+		if _, _, err := parseForwardSpec(spec); err == nil {
+			t.Errorf("parseForwardSpec(%q) should fail", spec)
+		}
+	}
+
+	if listen, err := parseForwardListen("8080"); err != nil || listen != "localhost:8080" {
+		t.Errorf("parseForwardListen = %s %v", listen, err)
+	}
+}
+
+// 转发连接，freeze 后丢弃发往服务器的数据，模拟连接中断
+type frozenRelay struct {
+	listener net.Listener
+	frozen   int32
+}
+
+func startFrozenRelay(t *testing.T, target string) *frozenRelay {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relay := &frozenRelay{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			remote, err := net.Dial("tcp", target)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+
+			go func() {
+				_, _ = io.Copy(conn, remote)
+			}()
+			go func() {
+				defer remote.Close()
+				buff := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buff)
+					if err != nil {
+						return
+					}
+					if atomic.LoadInt32(&relay.frozen) == 0 {
+						_, _ = remote.Write(buff[:n])
+					}
+				}
+			}()
+		}
+	}()
+
+	return relay
+}
+
+func TestEscapeDisconnect(t *testing.T) {
+	sshListener := startTestSshServer(t, "secret")
+	defer sshListener.Close()
+
+	relay := startFrozenRelay(t, sshListener.Addr().String())
+	defer relay.listener.Close()
+
+	addr := relay.listener.Addr().(*net.TCPAddr)
+	server := &Server{Name: "test", Ip: addr.IP.String(), Port: addr.Port, User: "test", Password: "secret", Method: "password"}
+	client, err := server.dialSshClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务器收不到关闭消息，~. 需直接关闭连接才能结束会话
+	atomic.StoreInt32(&relay.frozen, 1)
+	escape := &escapeInput{server: server, client: client, session: session, out: ioutil.Discard}
+	if escape.handle('.', nil) {
+		t.Error("~. should stop reading input")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = session.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not end after ~.")
+	}
+}
@@ -0,0 +1,249 @@
+package app
+
+import (
+	"autossh/src/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ansiStateNormal  = iota
+	ansiStateEsc     // 已读取 ESC
+	ansiStateCsi     // ESC [ ... 直到结束符
+	ansiStateOsc     // ESC ] ... 直到 BEL 或 ESC \
+	ansiStateOscEsc  // OSC 中读取到 ESC
+	ansiStateCharset // ESC ( 或 ESC ) 后跟一个字符
+)
+
+// 会话日志
+// 实现io.Writer，写入失败只记录错误，不影响终端输出
+type sessionLogger struct {
+	server *Server
+	config ServerLog
+
+	file     *os.File
+	flag     int
+	filename string
+	size     int64
+	openedAt time.Time
+
+	maxSize   int64
+	maxAge    time.Duration
+	lineStart bool
+	ansiState int
+}
+
+// 创建会话日志
+func newSessionLogger(server *Server) (*sessionLogger, error) {
+	logger := &sessionLogger{
+		server:    server,
+		config:    server.Log,
+		lineStart: true,
+	}
+
+	var err error
+	if server.Log.MaxSize != "" {
+		if logger.maxSize, err = utils.ParseSize(server.Log.MaxSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if server.Log.MaxAge != "" {
+		if logger.maxAge, err = time.ParseDuration(server.Log.MaxAge); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.flag = os.O_WRONLY | os.O_CREATE
+	if server.Log.Mode == LogModeCover {
+		logger.flag |= os.O_TRUNC
+	} else {
+		logger.flag |= os.O_APPEND
+	}
+
+	if err := logger.open(logger.flag); err != nil {
+		return nil, err
+	}
+
+	return logger, nil
+}
+
+// 打开日志文件
+func (logger *sessionLogger) open(flag int) error {
+	filename := logger.server.formatLogFilename(logger.config.Filename)
+	if strings.HasPrefix(filename, "~") {
+		filename, _ = utils.ParsePath(filename)
+	}
+
+	if dir := filepath.Dir(filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filename, flag, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	logger.file = f
+	logger.filename = filename
+	logger.size = info.Size()
+	logger.openedAt = time.Now()
+
+	return nil
+}
+
+// 日志轮转
+// 文件名不变时（未使用时间占位符），将旧文件重命名为 filename.YYYYmmddHHMMSS，同一秒内多次轮转时追加序号
+// 文件名变化时按配置的模式打开新文件，追加模式下不清空已存在的文件
+func (logger *sessionLogger) rotate() error {
+	if err := logger.file.Close(); err != nil {
+		return err
+	}
+
+	if filepath.Base(logger.server.formatLogFilename(logger.config.Filename)) == filepath.Base(logger.filename) {
+		stamp := logger.filename + "." + time.Now().Format("20060102150405")
+		backup := stamp
+		for i := 1; ; i++ {
+			if _, err := os.Lstat(backup); os.IsNotExist(err) {
+				break
+			}
+			backup = stamp + "." + strconv.Itoa(i)
+		}
+		if err := os.Rename(logger.filename, backup); err != nil {
+			return err
+		}
+	}
+
+	return logger.open(logger.flag)
+}
+
+// 是否需要轮转
+func (logger *sessionLogger) needRotate(n int) bool {
+	if logger.maxSize > 0 && logger.size > 0 && logger.size+int64(n) > logger.maxSize {
+		return true
+	}
+
+	if logger.maxAge > 0 && time.Now().Sub(logger.openedAt) > logger.maxAge {
+		return true
+	}
+
+	return false
+}
+
+func (logger *sessionLogger) Write(p []byte) (int, error) {
+	if logger.file == nil {
+		return len(p), nil
+	}
+
+	buff := logger.format(p)
+	if len(buff) == 0 {
+		return len(p), nil
+	}
+
+	if logger.needRotate(len(buff)) {
+		if err := logger.rotate(); err != nil {
+			utils.Logger.Category("server").Error("Rotate log file fail ", err)
+			logger.file = nil
+			return len(p), nil
+		}
+	}
+
+	n, err := logger.file.Write(buff)
+	logger.size += int64(n)
+	if err != nil {
+		utils.Logger.Category("server").Error("Write file buffer fail ", err)
+	}
+
+	return len(p), nil
+}
+
+func (logger *sessionLogger) Close() error {
+	if logger.file == nil {
+		return nil
+	}
+
+	err := logger.file.Close()
+	logger.file = nil
+	return err
+}
+
+// 格式化日志内容：去除ANSI控制字符、添加时间戳
+func (logger *sessionLogger) format(p []byte) []byte {
+	buff := make([]byte, 0, len(p))
+	for _, c := range p {
+		if logger.config.StripAnsi && !logger.keepAnsi(c) {
+			continue
+		}
+
+		if logger.config.Timestamp && logger.lineStart {
+			buff = append(buff, "["+time.Now().Format("2006-01-02 15:04:05")+"] "...)
+		}
+
+		buff = append(buff, c)
+		logger.lineStart = c == '\n'
+	}
+
+	return buff
+}
+
+// 判断字符是否需要保留，用于去除ANSI控制序列
+// 控制序列可能被拆分到多次Write中，因此需要记录状态
+func (logger *sessionLogger) keepAnsi(c byte) bool {
+	switch logger.ansiState {
+	case ansiStateEsc:
+		switch c {
+		case '[':
+			logger.ansiState = ansiStateCsi
+		case ']':
+			logger.ansiState = ansiStateOsc
+		case '(', ')', '*', '+', '#':
+			logger.ansiState = ansiStateCharset
+		default:
+			logger.ansiState = ansiStateNormal
+		}
+		return false
+	case ansiStateCsi:
+		if c >= 0x40 && c <= 0x7e {
+			logger.ansiState = ansiStateNormal
+		}
+		return false
+	case ansiStateOsc:
+		if c == 0x07 {
+			logger.ansiState = ansiStateNormal
+		} else if c == 0x1b {
+			logger.ansiState = ansiStateOscEsc
+		}
+		return false
+	case ansiStateOscEsc:
+		if c == '\\' {
+			logger.ansiState = ansiStateNormal
+		} else {
+			logger.ansiState = ansiStateOsc
+		}
+		return false
+	case ansiStateCharset:
+		logger.ansiState = ansiStateNormal
+		return false
+	}
+
+	switch c {
+	case 0x1b:
+		logger.ansiState = ansiStateEsc
+		return false
+	case '\r', 0x07, 0x08, 0x0e, 0x0f:
+		return false
+	}
+
+	return true
+}
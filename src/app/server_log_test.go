@@ -0,0 +1,123 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionLoggerStripAnsi(t *testing.T) {
+	cases := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"plain", []string{"hello\r\n"}, "hello\n"},
+		{"color", []string{"\x1b[1;32mok\x1b[0m done\n"}, "ok done\n"},
+		{"split csi", []string{"a\x1b", "[3", "1mb\x1b[0", "m\n"}, "ab\n"},
+		{"osc bel", []string{"\x1b]0;root@web: ~\x07$ ls\n"}, "$ ls\n"},
+		{"osc st", []string{"\x1b]2;title\x1b", "\\x\n"}, "x\n"},
+		{"charset", []string{"\x1b(Bline\x0f\n"}, "line\n"},
+		{"backspace and bell", []string{"ab\x08\x07c\n"}, "abc\n"},
+		{"utf8", []string{"\x1b[33m中文\x1b[m\n"}, "中文\n"},
+	}
+
+	for _, c := range cases {
+		logger := &sessionLogger{config: ServerLog{StripAnsi: true}, lineStart: true}
+		got := ""
+		for _, chunk := range c.chunks {
+			got += string(logger.format([]byte(chunk)))
+		}
+		if got != c.want {
+			t.Errorf("%s: format = %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	// 未开启时原样保留
+	logger := &sessionLogger{lineStart: true}
+	if got := string(logger.format([]byte("\x1b[31mred\r\n"))); got != "\x1b[31mred\r\n" {
+		t.Errorf("format without strip = %q", got)
+	}
+}
+
+func TestSessionLoggerTimestamp(t *testing.T) {
+	logger := &sessionLogger{config: ServerLog{Timestamp: true, StripAnsi: true}, lineStart: true}
+	got := string(logger.format([]byte("one\r\ntw"))) + string(logger.format([]byte("o\n\x1b[0m")))
+
+	stamp := `\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\] `
+	if !regexp.MustCompile(`^` + stamp + `one\n` + stamp + `two\n$`).MatchString(got) {
+		t.Errorf("format = %q", got)
+	}
+}
+
+func TestSessionLoggerRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autossh-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "%n.log")
+	server := &Server{Name: "web", Log: ServerLog{Enable: true, Filename: filename, MaxSize: "10"}}
+	logger, err := newSessionLogger(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	// 超出大小时轮转，同一秒内多次轮转不覆盖旧文件
+	for _, line := range []string{"12345678\n", "abcdefgh\n", "ABCDEFGH\n"} {
+		if _, err := logger.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "web.log*"))
+	if len(files) != 3 {
+		t.Fatalf("files = %v", files)
+	}
+	contents := make([]string, 0, len(files))
+	for _, file := range files {
+		b, _ := ioutil.ReadFile(file)
+		contents = append(contents, string(b))
+	}
+	all := strings.Join(contents, "")
+	for _, line := range []string{"12345678\n", "abcdefgh\n", "ABCDEFGH\n"} {
+		if strings.Count(all, line) != 1 {
+			t.Errorf("%q lost after rotation: %q", line, contents)
+		}
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "web.log")); string(b) != "ABCDEFGH\n" {
+		t.Errorf("current log = %q", b)
+	}
+
+	// 超过时长时轮转
+	logger.maxSize = 0
+	logger.maxAge = time.Hour
+	if logger.needRotate(1) {
+		t.Error("rotate before max age")
+	}
+	logger.openedAt = time.Now().Add(-2 * time.Hour)
+	if !logger.needRotate(1) {
+		t.Error("no rotate after max age")
+	}
+
+	// 文件名变化（如按日期命名）时，追加模式不清空已存在的文件
+	logger.maxAge = 0
+	logger.maxSize = 10
+	logger.filename = filepath.Join(dir, "yesterday.log")
+	if _, err := logger.Write([]byte("12345678\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "web.log")); string(b) != "ABCDEFGH\n12345678\n" {
+		t.Errorf("appended log = %q", b)
+	}
+
+	if _, err := newSessionLogger(&Server{Log: ServerLog{Filename: filename, MaxSize: "ten"}}); err == nil {
+		t.Error("invalid max size should fail")
+	}
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// 远程执行 sz 时发送的 ZRQINIT 头
+	zmodemZrqinit = []byte("**\x18B00000000000000")
+	// 远程执行 rz 时发送的 ZRINIT 头，标志位及校验不固定，仅匹配前缀
+	zmodemZrinit = []byte("**\x18B01")
+	// 取消传输，8个 CAN 加10个退格
+	zmodemAbort = []byte("\x18\x18\x18\x18\x18\x18\x18\x18\b\b\b\b\b\b\b\b\b\b")
+)
+
+const (
+	zmodemIdle = iota
+	zmodemPrompting
+	zmodemActive
+)
+
+// 检测会话输出中的 ZMODEM 传输，交由本地的 rz/sz（lrzsz）处理
+// 远程执行 sz 时接收文件到下载目录，远程执行 rz 时提示输入要上传的文件
+type zmodemSession struct {
+	next  io.Writer // 终端、日志等原有的输出
+	stdin io.Writer // 会话的输入
+	out   io.Writer
+	dir   string // 下载目录
+
+	mu          sync.Mutex
+	state       int
+	tail        []byte // 上次输出的末尾，用于匹配被拆分的头
+	line        []byte // 上传时输入的文件路径
+	skipOO      bool   // 传输结束后跳过发送方的 OO
+	cmd         *exec.Cmd
+	procIn      io.WriteCloser
+	transferred *byteCounter
+	canceled    bool
+}
+
+func newZmodemSession(server *Server, next io.Writer, stdin io.Writer) *zmodemSession {
+	return &zmodemSession{
+		next:  next,
+		stdin: stdin,
+		out:   os.Stdout,
+		dir:   zmodemDir(server),
+	}
+}
+
+// 下载目录，可通过 ZmodemDir 选项指定，默认为 ~/Downloads，不存在时为当前目录
+func zmodemDir(server *Server) string {
+	dir, _ := server.Options["ZmodemDir"].(string)
+	if dir == "" {
+		dir = "~/Downloads"
+		if file, err := utils.ParsePath(dir); err != nil {
+			return "."
+		} else if exists, _ := utils.FileIsExists(file); !exists {
+			return "."
+		}
+	}
+
+	if file, err := utils.ParsePath(dir); err == nil {
+		return file
+	}
+
+	return dir
+}
+
+func (zmodem *zmodemSession) Write(p []byte) (int, error) {
+	zmodem.mu.Lock()
+	defer zmodem.mu.Unlock()
+
+	switch zmodem.state {
+	case zmodemActive:
+		// 写入时不持有锁，以便传输中可以取消；本地进程已退出时丢弃剩余的数据
+		procIn, transferred := zmodem.procIn, zmodem.transferred
+		zmodem.mu.Unlock()
+		if _, err := procIn.Write(p); err == nil {
+			_, _ = transferred.Write(p)
+		}
+		zmodem.mu.Lock()
+		return len(p), nil
+	case zmodemPrompting:
+		// 等待输入期间远程会重复发送 ZRINIT，直接丢弃
+		return len(p), nil
+	}
+
+	data := p
+	if zmodem.skipOO {
+		zmodem.skipOO = false
+		data = bytes.TrimPrefix(data, []byte("OO"))
+	}
+
+	combined := append(append([]byte{}, zmodem.tail...), data...)
+	index, upload := detectZmodem(combined)
+	if index == -1 {
+		zmodem.keepTail(combined)
+		_, err := zmodem.next.Write(data)
+		return len(p), err
+	}
+
+	// 头之前的内容正常输出，已输出过的部分不重复输出
+	if offset := index - len(zmodem.tail); offset > 0 {
+		_, _ = zmodem.next.Write(data[:offset])
+	}
+	header := combined[index:]
+	zmodem.tail = nil
+
+	if upload {
+		zmodem.prompt()
+	} else if err := zmodem.start(zmodemCommand("rz", "-b", "-E"), true, header); err != nil {
+		zmodem.abort(err)
+	}
+
+	return len(p), nil
+}
+
+// 查找 ZMODEM 头，返回位置及是否为上传
+func detectZmodem(b []byte) (int, bool) {
+	if i := bytes.Index(b, zmodemZrqinit); i != -1 {
+		return i, false
+	}
+	if i := bytes.Index(b, zmodemZrinit); i != -1 {
+		return i, true
+	}
+
+	return -1, false
+}
+
+// 保留末尾可能是头的一部分的内容
+func (zmodem *zmodemSession) keepTail(b []byte) {
+	size := len(zmodemZrqinit) - 1
+	if len(b) > size {
+		b = b[len(b)-size:]
+	}
+	zmodem.tail = append(zmodem.tail[:0:0], b...)
+}
+
+// 查找本地的 lrzsz 命令，部分系统命名为 lrz、lsz
+func zmodemCommand(name string, args ...string) *exec.Cmd {
+	for _, file := range []string{name, "l" + name} {
+		if path, err := exec.LookPath(file); err == nil {
+			return exec.Command(path, args...)
+		}
+	}
+
+	return nil
+}
+
+// 启动本地进程，header 为已收到的数据
+func (zmodem *zmodemSession) start(cmd *exec.Cmd, download bool, header []byte) error {
+	if cmd == nil {
+		return errors.New(i18n.T("zmodem.not_installed"))
+	}
+
+	procIn, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	// 下载时统计收到的数据量，上传时统计发送的数据量
+	transferred := new(byteCounter)
+	var stderr bytes.Buffer
+	cmd.Dir = zmodem.dir
+	cmd.Stdout = zmodem.stdin
+	if !download {
+		cmd.Stdout = io.MultiWriter(zmodem.stdin, transferred)
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	zmodem.cmd = cmd
+	zmodem.procIn = procIn
+	zmodem.transferred = transferred
+	zmodem.canceled = false
+	zmodem.state = zmodemActive
+
+	if len(header) > 0 {
+		if _, err := procIn.Write(header); err == nil {
+			_, _ = transferred.Write(header)
+		}
+	}
+
+	go zmodem.wait(cmd, download, transferred, &stderr)
+	return nil
+}
+
+// 等待本地进程结束并显示进度
+func (zmodem *zmodemSession) wait(cmd *exec.Cmd, download bool, transferred *byteCounter, stderr *bytes.Buffer) {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	started := time.Now()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	var err error
+	for waiting := true; waiting; {
+		select {
+		case <-ticker.C:
+			printZmodemProgress(download, transferred, started)
+		case err = <-done:
+			waiting = false
+		}
+	}
+
+	zmodem.mu.Lock()
+	defer zmodem.mu.Unlock()
+
+	zmodem.state = zmodemIdle
+	zmodem.procIn = nil
+	zmodem.cmd = nil
+	zmodem.skipOO = true
+
+	printZmodemProgress(download, transferred, started)
+	switch {
+	case zmodem.canceled:
+		zmodem.println(i18n.T("zmodem.canceled"))
+	case err != nil:
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		zmodem.println(i18n.T("zmodem.failed", strings.Replace(msg, "\n", "\r\n", -1)))
+	case download:
+		zmodem.println(i18n.T("zmodem.received", zmodem.dir))
+	default:
+		zmodem.println(i18n.T("zmodem.sent"))
+	}
+}
+
+// 显示已传输的字节数及速度
+func printZmodemProgress(download bool, transferred *byteCounter, started time.Time) {
+	size := float64(transferred.count())
+	speed := size / time.Since(started).Seconds()
+
+	key := "zmodem.progress_sent"
+	if download {
+		key = "zmodem.progress_received"
+	}
+
+	_, _ = fmt.Fprint(os.Stderr, "\r\x1b[K"+i18n.T(key, utils.SizeFormat(size), utils.SizeFormat(speed)))
+}
+
+// 提示输入要上传的文件，输入由 input 处理
+// 终端保持 raw 模式，Ctrl+C 不会产生 SIGINT 结束程序，由 input 处理回显及取消
+func (zmodem *zmodemSession) prompt() {
+	zmodem.state = zmodemPrompting
+	zmodem.line = zmodem.line[:0]
+
+	_, _ = fmt.Fprint(zmodem.out, "\r\n"+i18n.T("zmodem.prompt_upload"))
+}
+
+// 处理用户输入，返回 true 时表示已处理，不再发送到服务器
+// 等待输入文件时读取一行，输入及传输中按 Ctrl+C 取消
+func (zmodem *zmodemSession) input(c byte) bool {
+	zmodem.mu.Lock()
+	defer zmodem.mu.Unlock()
+
+	switch zmodem.state {
+	case zmodemPrompting:
+		switch c {
+		case 0x03:
+			zmodem.abort(errors.New(i18n.T("zmodem.canceled")))
+			return true
+		case '\r', '\n':
+		case 0x7f, '\b':
+			// 删除最后一个字符，按 UTF-8 处理多字节字符
+			if len(zmodem.line) > 0 {
+				_, size := utf8.DecodeLastRune(zmodem.line)
+				zmodem.line = zmodem.line[:len(zmodem.line)-size]
+				_, _ = fmt.Fprint(zmodem.out, "\b \b")
+			}
+			return true
+		default:
+			zmodem.line = append(zmodem.line, c)
+			_, _ = zmodem.out.Write([]byte{c})
+			return true
+		}
+
+		_, _ = fmt.Fprint(zmodem.out, "\r\n")
+		files, err := uploadFiles(string(zmodem.line))
+		if err == nil && len(files) == 0 {
+			err = errors.New(i18n.T("zmodem.canceled"))
+		}
+		if err == nil {
+			err = zmodem.start(zmodemCommand("sz", append([]string{"-b"}, files...)...), false, nil)
+		}
+		if err != nil {
+			zmodem.abort(err)
+		}
+		return true
+	case zmodemActive:
+		if c == 0x03 && zmodem.cmd != nil {
+			zmodem.canceled = true
+			_ = zmodem.cmd.Process.Kill()
+			_, _ = zmodem.stdin.Write(zmodemAbort)
+		}
+		return true
+	}
+
+	return false
+}
+
+// 解析要上传的文件，以空格分隔，支持 ~
+func uploadFiles(line string) ([]string, error) {
+	files := make([]string, 0)
+	for _, field := range strings.Fields(line) {
+		file, err := utils.ParsePath(field)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists, _ := utils.FileIsExists(file); !exists {
+			return nil, errors.New(i18n.T("zmodem.file_not_found", field))
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// 取消远程的传输并提示原因
+func (zmodem *zmodemSession) abort(err error) {
+	zmodem.state = zmodemIdle
+	_, _ = zmodem.stdin.Write(zmodemAbort)
+	zmodem.println(err.Error())
+}
+
+// 终端处于 raw 模式，需使用 \r\n 换行
+func (zmodem *zmodemSession) println(msg string) {
+	_, _ = fmt.Fprint(zmodem.out, "\r\n"+msg+"\r\n")
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDetectZmodem(t *testing.T) {
+	cases := []struct {
+		output string
+		index  int
+		upload bool
+	}{
+		{"hello\r\n", -1, false},
+		{"rz\r**\x18B00000000000000\r\x8a\x11", 3, false},
+		{"**\x18B0100000023be50\r\x8a\x11", 0, true},
+	}
+
+	for _, c := range cases {
+		index, upload := detectZmodem([]byte(c.output))
+		if index != c.index || upload != c.upload {
+			t.Errorf("detectZmodem(%q) = %d %v, want %d %v", c.output, index, upload, c.index, c.upload)
+		}
+	}
+}
+
+func TestZmodemWrite(t *testing.T) {
+	// 本地未安装 lrzsz 时取消远程的传输
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	_ = os.Setenv("PATH", "")
+
+	var next, stdin bytes.Buffer
+	zmodem := &zmodemSession{next: &next, stdin: &stdin, out: ioutil.Discard}
+
+	_, _ = zmodem.Write([]byte("$ sz a.txt\r\n**\x18B0000"))
+	_, _ = zmodem.Write([]byte("0000000000\r\x8a\x11"))
+
+	if next.String() != "$ sz a.txt\r\n**\x18B0000" {
+		t.Errorf("output = %q", next.String())
+	}
+	if !bytes.Equal(stdin.Bytes(), zmodemAbort) {
+		t.Errorf("stdin = %q, want abort", stdin.Bytes())
+	}
+	if zmodem.state != zmodemIdle {
+		t.Errorf("state = %d", zmodem.state)
+	}
+
+	_, _ = zmodem.Write([]byte("$ "))
+	if next.String() != "$ sz a.txt\r\n**\x18B0000$ " {
+		t.Errorf("output after abort = %q", next.String())
+	}
+}
+
+func TestZmodemPrompt(t *testing.T) {
+	var next, stdin, out bytes.Buffer
+	zmodem := &zmodemSession{next: &next, stdin: &stdin, out: &out}
+
+	// 远程执行 rz 时提示输入，输入由本地回显，不发送到服务器
+	_, _ = zmodem.Write([]byte("**\x18B0100000023be50\r\x8a\x11"))
+	if zmodem.state != zmodemPrompting {
+		t.Fatalf("state = %d", zmodem.state)
+	}
+	for _, c := range []byte("a.tx文\x7f\x7ft") {
+		if !zmodem.input(c) {
+			t.Errorf("input %q sent to server", c)
+		}
+	}
+	if string(zmodem.line) != "a.tt" || !bytes.HasSuffix(out.Bytes(), []byte("\b \b\b \bt")) {
+		t.Errorf("line = %q, out = %q", zmodem.line, out.String())
+	}
+
+	// Ctrl+C 取消上传
+	zmodem.input(0x03)
+	if !bytes.Equal(stdin.Bytes(), zmodemAbort) || zmodem.state != zmodemIdle {
+		t.Errorf("stdin = %q, state = %d", stdin.Bytes(), zmodem.state)
+	}
+	if zmodem.input('x') {
+		t.Error("input after cancel should be sent to server")
+	}
+}
@@ -0,0 +1,446 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"flag"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ClusterLayoutPrefix = "prefix" // 输出交错显示，每行添加主机前缀
+	ClusterLayoutTmux   = "tmux"   // 在tmux中分屏显示，并开启同步输入
+
+	clusterControlKey = 0x1d // Ctrl-]
+)
+
+// 集群中的主机
+type clusterHost struct {
+	index   string
+	server  *Server
+	label   string
+	enabled bool
+	closed  bool
+
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+}
+
+type Cluster struct {
+	cfg    *Config
+	layout string
+	hosts  []*clusterHost
+
+	mu        sync.Mutex // 输出锁
+	control   bool       // 是否处于控制模式
+	inputLine []byte     // 控制模式下的输入
+}
+
+// 集群模式
+func showCluster(configFile string) {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	cluster := Cluster{cfg: cfg}
+	if err := cluster.parse(); err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	if cluster.layout == ClusterLayoutTmux {
+		err = cluster.runTmux(configFile)
+	} else {
+		err = cluster.run()
+	}
+
+	if err != nil {
+		utils.Errorln(err)
+	}
+}
+
+// 解析参数
+func (cluster *Cluster) parse() error {
+	fs := flag.NewFlagSet("cluster", flag.ContinueOnError)
+	fs.StringVar(&cluster.layout, "layout", ClusterLayoutPrefix, i18n.T("flag.cluster_layout"))
+	if err := fs.Parse(flag.Args()[1:]); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New(i18n.T("cluster.no_targets"))
+	}
+
+	if cluster.layout != ClusterLayoutPrefix && cluster.layout != ClusterLayoutTmux {
+		return errors.New(i18n.T("cluster.invalid_layout", cluster.layout))
+	}
+
+	indexes, err := cluster.cfg.resolveTargets(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	maxLen := 0
+	for _, index := range indexes {
+		server := cluster.cfg.serverIndex[index].server
+		if length := utils.ZhLen(server.Name); length > maxLen {
+			maxLen = length
+		}
+		cluster.hosts = append(cluster.hosts, &clusterHost{
+			index:   index,
+			server:  server,
+			enabled: true,
+		})
+	}
+
+	for _, host := range cluster.hosts {
+		host.label = host.server.Name + strings.Repeat(" ", maxLen-utils.ZhLen(host.server.Name))
+	}
+
+	return nil
+}
+
+// 在tmux新窗口中为每台服务器打开一个面板，并开启同步输入
+func (cluster *Cluster) runTmux(configFile string) error {
+	if os.Getenv("TMUX") == "" {
+		return errors.New(i18n.T("cluster.tmux_required"))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmdline := func(host *clusterHost) string {
+		return utils.ShellQuote(exe) + " -c " + utils.ShellQuote(configFile) + " " + utils.ShellQuote(host.index)
+	}
+
+	output, err := exec.Command("tmux", "new-window", "-P", "-F", "#{window_id}", "-n", "autossh-cluster", cmdline(cluster.hosts[0])).Output()
+	if err != nil {
+		return errors.New(i18n.T("cluster.tmux_window_failed", err))
+	}
+	window := strings.TrimSpace(string(output))
+
+	for _, host := range cluster.hosts[1:] {
+		if err := exec.Command("tmux", "split-window", "-t", window, cmdline(host)).Run(); err != nil {
+			return errors.New(i18n.T("cluster.tmux_pane_failed", err))
+		}
+		_ = exec.Command("tmux", "select-layout", "-t", window, "tiled").Run()
+	}
+
+	return exec.Command("tmux", "set-window-option", "-t", window, "synchronize-panes", "on").Run()
+}
+
+// 连接所有服务器，广播输入，输出添加主机前缀
+func (cluster *Cluster) run() error {
+	fd := int(os.Stdin.Fd())
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(cluster.hosts))
+	for i, host := range cluster.hosts {
+		wg.Add(1)
+		go func(i int, host *clusterHost) {
+			defer wg.Done()
+			errs[i] = cluster.connect(host, fd)
+		}(i, host)
+	}
+	wg.Wait()
+
+	connected := 0
+	for i, host := range cluster.hosts {
+		if errs[i] != nil {
+			host.closed = true
+			host.enabled = false
+			utils.Errorln("[" + host.server.Name + "] " + errs[i].Error())
+		} else {
+			connected++
+		}
+	}
+
+	if connected == 0 {
+		return errors.New(i18n.T("cluster.no_connections"))
+	}
+
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return errors.New(i18n.T("server.fd_failed", err))
+	}
+	defer terminal.Restore(fd, oldState)
+
+	defer cluster.close()
+
+	cluster.notice(i18n.T("cluster.connected", connected))
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, host := range cluster.hosts {
+			if host.session == nil {
+				continue
+			}
+
+			wg.Add(1)
+			go func(host *clusterHost) {
+				defer wg.Done()
+				_ = host.session.Wait()
+				cluster.mu.Lock()
+				host.closed = true
+				host.enabled = false
+				cluster.mu.Unlock()
+				cluster.notice(i18n.T("cluster.disconnected", host.server.Name))
+			}(host)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	quit := make(chan struct{})
+	go cluster.broadcast(quit)
+
+	select {
+	case <-done:
+	case <-quit:
+	}
+
+	return nil
+}
+
+// 连接单台服务器并打开Shell
+func (cluster *Cluster) connect(host *clusterHost, fd int) error {
+	client, err := host.server.GetSshClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		_ = client.Close()
+		return err
+	}
+
+	host.client = client
+	host.session = session
+
+	host.stdin, err = session.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	writer := &clusterWriter{cluster: cluster, host: host, lineStart: true}
+	session.Stdout = writer
+	session.Stderr = writer
+
+	if err := host.server.requestPty(session, fd); err != nil {
+		return err
+	}
+	host.server.listenWindowChange(session, fd)
+
+	return session.Shell()
+}
+
+// 关闭所有连接
+func (cluster *Cluster) close() {
+	for _, host := range cluster.hosts {
+		if host.session != nil {
+			_ = host.session.Close()
+		}
+		if host.client != nil {
+			_ = host.client.Close()
+		}
+	}
+}
+
+// 读取标准输入并广播，Ctrl-] 进入控制模式
+func (cluster *Cluster) broadcast(quit chan struct{}) {
+	buff := make([]byte, 1024)
+	for {
+		n, err := os.Stdin.Read(buff)
+		if err != nil {
+			close(quit)
+			return
+		}
+
+		data := make([]byte, 0, n)
+		for _, c := range buff[:n] {
+			if cluster.control {
+				if !cluster.handleControl(c, &data) {
+					close(quit)
+					return
+				}
+				continue
+			}
+
+			if c == clusterControlKey {
+				cluster.control = true
+				cluster.inputLine = cluster.inputLine[:0]
+				cluster.write([]byte("\r\n" + clusterPrompt()))
+				continue
+			}
+
+			data = append(data, c)
+		}
+
+		cluster.send(data)
+	}
+}
+
+// 处理控制模式输入，返回false表示退出集群模式
+func (cluster *Cluster) handleControl(c byte, data *[]byte) bool {
+	switch c {
+	case clusterControlKey:
+		// 连按两次发送 Ctrl-] 本身
+		if len(cluster.inputLine) == 0 {
+			cluster.control = false
+			cluster.write([]byte("\r\n"))
+			*data = append(*data, c)
+		}
+	case 0x03:
+		cluster.control = false
+		cluster.write([]byte("^C\r\n"))
+	case 0x7f, 0x08:
+		if len(cluster.inputLine) > 0 {
+			cluster.inputLine = cluster.inputLine[:len(cluster.inputLine)-1]
+			cluster.write([]byte("\b \b"))
+		}
+	case '\r', '\n':
+		cluster.control = false
+		cluster.write([]byte("\r\n"))
+		return cluster.execControl(strings.TrimSpace(string(cluster.inputLine)))
+	default:
+		cluster.inputLine = append(cluster.inputLine, c)
+		cluster.write([]byte{c})
+	}
+
+	return true
+}
+
+func clusterPrompt() string {
+	return "\033[33m" + i18n.T("cluster.prompt") + "\033[0m"
+}
+
+// 执行控制命令
+func (cluster *Cluster) execControl(cmd string) bool {
+	switch cmd {
+	case "":
+	case "q":
+		return false
+	case "a":
+		cluster.mu.Lock()
+		for _, host := range cluster.hosts {
+			host.enabled = !host.closed
+		}
+		cluster.mu.Unlock()
+		cluster.list()
+	case "l":
+		cluster.list()
+	default:
+		i, err := strconv.Atoi(cmd)
+		if err != nil || i < 1 || i > len(cluster.hosts) {
+			cluster.notice(i18n.T("cluster.invalid_input", cmd))
+			break
+		}
+
+		host := cluster.hosts[i-1]
+		cluster.mu.Lock()
+		if !host.closed {
+			host.enabled = !host.enabled
+		}
+		cluster.mu.Unlock()
+		cluster.list()
+	}
+
+	return true
+}
+
+// 输出主机列表及状态
+// 主机状态会在会话结束时被修改，需在锁内读取，write 同样使用该锁，解锁后再输出
+func (cluster *Cluster) list() {
+	lines := make([]string, 0, len(cluster.hosts))
+	cluster.mu.Lock()
+	for i, host := range cluster.hosts {
+		status := "\033[32m on\033[0m"
+		if host.closed {
+			status = "\033[31m closed\033[0m"
+		} else if !host.enabled {
+			status = "\033[90m off\033[0m"
+		}
+		lines = append(lines, " ["+strconv.Itoa(i+1)+"] "+host.label+status)
+	}
+	cluster.mu.Unlock()
+
+	cluster.write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+}
+
+// 向已启用的主机发送输入
+func (cluster *Cluster) send(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	cluster.mu.Lock()
+	hosts := make([]*clusterHost, 0, len(cluster.hosts))
+	for _, host := range cluster.hosts {
+		if host.enabled && host.stdin != nil {
+			hosts = append(hosts, host)
+		}
+	}
+	cluster.mu.Unlock()
+
+	for _, host := range hosts {
+		if _, err := host.stdin.Write(data); err != nil {
+			utils.Logger.Category("cluster").Error("write stdin fail ", host.server.Name, err)
+		}
+	}
+}
+
+// 输出提示信息
+func (cluster *Cluster) notice(msg string) {
+	cluster.write([]byte("\r\n\033[33m[autossh] " + msg + "\033[0m\r\n"))
+}
+
+func (cluster *Cluster) write(p []byte) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	_, _ = os.Stdout.Write(p)
+}
+
+// 主机输出，每行添加主机前缀，未启用的主机前缀显示为灰色
+type clusterWriter struct {
+	cluster   *Cluster
+	host      *clusterHost
+	lineStart bool
+}
+
+func (writer *clusterWriter) Write(p []byte) (int, error) {
+	writer.cluster.mu.Lock()
+	defer writer.cluster.mu.Unlock()
+
+	color := "\033[36m"
+	if !writer.host.enabled {
+		color = "\033[90m"
+	}
+	prefix := color + "[" + writer.host.label + "]\033[0m "
+
+	buff := make([]byte, 0, len(p)+len(prefix))
+	for _, c := range p {
+		if writer.lineStart {
+			buff = append(buff, prefix...)
+		}
+		buff = append(buff, c)
+		writer.lineStart = c == '\n'
+	}
+
+	_, err := os.Stdout.Write(buff)
+	return len(p), err
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveTargets(t *testing.T) {
+	cfg, clean := loadTestConfig(t, `{
+		"servers": [{"name": "s1", "ip": "10.0.0.1", "alias": "web"}, {"name": "s2", "ip": "10.0.0.2"}],
+		"groups": [
+			{"group_name": "c", "prefix": "c", "servers": [{"name": "c1", "ip": "10.0.1.1"}], "groups": [
+				{"group_name": "p", "prefix": "p", "servers": [{"name": "p1", "ip": "10.0.2.1"}]}
+			]},
+			{"group_name": "d", "prefix": "d", "servers": [{"name": "d1", "ip": "10.0.3.1"}]}
+		]
+	}`)
+	defer clean()
+
+	cases := []struct {
+		targets []string
+		want    string
+		err     bool
+	}{
+		{[]string{"1"}, "1", false},
+		{[]string{"web"}, "1", false},
+		{[]string{"web", "1", "1,2"}, "1,2", false},
+		{[]string{"c"}, "c1,c.p.1", false},
+		{[]string{"c.p"}, "c.p.1", false},
+		{[]string{" d1 , ,c.p.1"}, "d1,c.p.1", false},
+		{[]string{"all"}, "1,2,c1,c.p.1,d1", false},
+		{[]string{"1", "nope"}, "", true},
+		{[]string{"p"}, "", true},
+	}
+
+	for _, c := range cases {
+		indexes, err := cfg.resolveTargets(c.targets)
+		if (err != nil) != c.err || (!c.err && strings.Join(indexes, ",") != c.want) {
+			t.Errorf("resolveTargets(%v) = %v %v, want %s", c.targets, indexes, err, c.want)
+		}
+	}
+}
+
+// 可关闭的缓冲区，作为主机的输入
+type clusterTestStdin struct {
+	bytes.Buffer
+}
+
+func (stdin *clusterTestStdin) Close() error {
+	return nil
+}
+
+func TestClusterControl(t *testing.T) {
+	// 控制模式的提示写入标准输出，测试时丢弃
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	newCluster := func() (*Cluster, []*clusterTestStdin) {
+		cluster := &Cluster{}
+		stdins := make([]*clusterTestStdin, 3)
+		for i := range stdins {
+			stdins[i] = new(clusterTestStdin)
+			cluster.hosts = append(cluster.hosts, &clusterHost{server: &Server{}, enabled: true, stdin: stdins[i]})
+		}
+		cluster.hosts[2].closed = true
+		cluster.hosts[2].enabled = false
+		return cluster, stdins
+	}
+
+	cases := []struct {
+		name    string
+		input   string
+		enabled string // 各主机是否启用
+		data    string // 控制模式中产生的需要发送的数据
+		quit    bool
+	}{
+		{"toggle", "2\r", "yn n", "", false},
+		{"toggle twice", "2\r\x1d2\n", "yy n", "", false},
+		{"closed host", "3\r", "yy n", "", false},
+		{"backspace", "12\x7f\r", "ny n", "", false},
+		{"enable all", "1\r\x1d2\r\x1da\r", "yy n", "", false},
+		{"invalid", "9\r\x1dx\r", "yy n", "", false},
+		{"cancel", "1\x03", "yy n", "", false},
+		{"send ctrl-]", "\x1d", "yy n", "\x1d", false},
+		{"quit", " q \r", "yy n", "", true},
+	}
+
+	for _, c := range cases {
+		cluster, _ := newCluster()
+		cluster.control = true
+
+		var data []byte
+		quit := false
+		for _, b := range []byte(c.input) {
+			if !cluster.control {
+				if b != clusterControlKey {
+					t.Fatalf("%s: unexpected byte %q outside control mode", c.name, b)
+				}
+				cluster.control = true
+				cluster.inputLine = cluster.inputLine[:0]
+				continue
+			}
+			if !cluster.handleControl(b, &data) {
+				quit = true
+				break
+			}
+		}
+
+		enabled := ""
+		for i, host := range cluster.hosts {
+			if i == 2 {
+				enabled += " "
+			}
+			if host.enabled {
+				enabled += "y"
+			} else {
+				enabled += "n"
+			}
+		}
+		if enabled != c.enabled || string(data) != c.data || quit != c.quit || (!quit && cluster.control) {
+			t.Errorf("%s: enabled %q data %q quit %v control %v", c.name, enabled, data, quit, cluster.control)
+		}
+	}
+
+	// 只发送到已启用的主机
+	cluster, stdins := newCluster()
+	cluster.hosts[0].enabled = false
+	cluster.send([]byte("ls\r"))
+	if stdins[0].Len() != 0 || stdins[1].String() != "ls\r" || stdins[2].Len() != 0 {
+		t.Errorf("sent %q %q %q", stdins[0].String(), stdins[1].String(), stdins[2].String())
+	}
+
+	// 会话结束时修改主机状态，与输出列表同时进行（配合 -race 检查）
+	started, stop, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			cluster.mu.Lock()
+			cluster.hosts[1].enabled = !cluster.hosts[1].enabled
+			cluster.mu.Unlock()
+			if i == 0 {
+				close(started)
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+	<-started
+	for i := 0; i < 100; i++ {
+		cluster.list()
+	}
+	close(stop)
+	<-done
+}
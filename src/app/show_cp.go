@@ -1,21 +1,28 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"flag"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
 	"io"
 	"os"
 	"path"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
 )
 
+// 默认的读写缓冲区大小
+const defaultCpBuffer = 64 * 1024
+
 type ResType int
 
 const (
@@ -31,11 +38,70 @@ type TransferObject struct {
 }
 
 type Cp struct {
-	isDir bool
-	cfg   *Config
+	isDir    bool
+	cfg      *Config
+	buffer   int          // 读写缓冲区大小
+	limit    *rateLimiter // 限速，为空时不限速
+	includes patternsFlag // 复制目录时只传输匹配的文件
+	excludes patternsFlag // 复制目录时跳过匹配的文件及目录
+	tar      bool         // 打包传输
+	compress string       // 打包传输时的压缩方式
 
 	sources []*TransferObject
 	target  *TransferObject
+
+	sftpClients map[*Server]*sftp.Client
+	sshClients  map[*Server]*ssh.Client
+	stats       cpStats
+}
+
+// 可重复指定的匹配规则，如 -exclude '*.log' -exclude .git
+type patternsFlag []string
+
+func (patterns *patternsFlag) String() string {
+	return strings.Join(*patterns, ",")
+}
+
+func (patterns *patternsFlag) Set(value string) error {
+	if _, err := path.Match(value, ""); err != nil {
+		return errors.New(i18n.T("cp.invalid_pattern", value))
+	}
+
+	*patterns = append(*patterns, value)
+	return nil
+}
+
+// 规则包含 / 时匹配相对于源目录的路径，否则匹配文件名
+func (patterns patternsFlag) match(rel string) bool {
+	for _, pattern := range patterns {
+		name := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			name = rel
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 目录遍历时是否传输，匹配 exclude 的文件及目录跳过，指定 include 时文件需匹配其中之一
+func (cp *Cp) shouldTransfer(rel string, isDir bool) bool {
+	if cp.excludes.match(rel) {
+		return false
+	}
+
+	return isDir || len(cp.includes) == 0 || cp.includes.match(rel)
+}
+
+// 传输统计
+type cpStats struct {
+	files  int
+	bytes  int64
+	failed int
 }
 
 // 复制
@@ -48,37 +114,48 @@ func showCp(configFile string) {
 	}
 
 	cp := Cp{cfg: cfg}
-	if err := cp.parse(); err != nil {
+	if err := cp.parse(flag.Args()[1:]); err != nil {
 		utils.Errorln(err)
 		return
 	}
 
+	cp.run()
+}
+
+// 执行复制，单个文件失败时继续复制其他文件
+func (cp *Cp) run() {
+	defer cp.closeSftpClients()
+
+	startTime := time.Now()
+	defer func() {
+		cp.printSummary(time.Since(startTime))
+	}()
+
+	if cp.tar {
+		cp.runTar()
+		return
+	}
+
 	var dstIoClient IOClient
 	if cp.target.server == nil {
 		dstIoClient = new(LocalIOClient)
 	} else {
-		sftpClient, err := cp.target.server.GetSftpClient()
+		sftpClient, err := cp.getSftpClient(cp.target.server)
 		if err != nil {
 			utils.Errorln(err)
 			return
 		}
 
-		defer func() {
-			_ = sftpClient.Close()
-		}()
-
-		c := SftpIOClient{SftpClient: sftpClient}
-		dstIoClient = &c
+		dstIoClient = &SftpIOClient{SftpClient: sftpClient}
 	}
 
 	for _, source := range cp.sources {
 		var srcIoClient IOClient
-		var sftpClient *sftp.Client
 
 		if source.server == nil {
 			srcIoClient = new(LocalIOClient)
 		} else {
-			sftpClient, err := source.server.GetSftpClient()
+			sftpClient, err := cp.getSftpClient(source.server)
 			if err != nil {
 				cp.printFileError(source.path, err)
 				continue
@@ -87,48 +164,110 @@ func showCp(configFile string) {
 			srcIoClient = &SftpIOClient{SftpClient: sftpClient}
 		}
 
-		func() {
-			defer func() {
-				if sftpClient != nil {
-					_ = sftpClient.Close()
-				}
-			}()
+		files, err := expandGlob(srcIoClient, source.path)
+		if err != nil {
+			cp.printFileError(source.path, err)
+			continue
+		}
 
-			if file, err := cp.transferNew(srcIoClient, dstIoClient, source.path, cp.target.path, ""); err != nil {
+		for _, file := range files {
+			if file, err := cp.transferNew(srcIoClient, dstIoClient, file, cp.target.path, ""); err != nil {
 				cp.printFileError(file, err)
 			}
-		}()
+		}
+	}
+}
+
+// 获取Sftp Client，同一服务器的多个源共用一个连接
+func (cp *Cp) getSftpClient(server *Server) (*sftp.Client, error) {
+	if client, ok := cp.sftpClients[server]; ok {
+		return client, nil
+	}
+
+	client, err := server.GetSftpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if cp.sftpClients == nil {
+		cp.sftpClients = make(map[*Server]*sftp.Client)
+	}
+	cp.sftpClients[server] = client
+
+	return client, nil
+}
+
+func (cp *Cp) closeSftpClients() {
+	for _, client := range cp.sftpClients {
+		_ = client.Close()
 	}
 }
 
 // 解析参数
-func (cp *Cp) parse() error {
-	os.Args = flag.Args()
-	flag.BoolVar(&cp.isDir, "r", false, "文件夹")
-	flag.Parse()
+func (cp *Cp) parse(args []string) error {
+	return cp.parseWith(args, func(raw string) (*TransferObject, error) {
+		return newTransferObject(*cp.cfg, raw)
+	})
+}
+
+// 解析参数，newObject 用于解析源及目标
+func (cp *Cp) parseWith(args []string, newObject func(raw string) (*TransferObject, error)) error {
+	var limit, buffer string
+	fs := flag.NewFlagSet("cp", flag.ContinueOnError)
+	fs.BoolVar(&cp.isDir, "r", false, i18n.T("flag.cp_dir"))
+	fs.StringVar(&limit, "limit", "", i18n.T("flag.cp_limit"))
+	fs.StringVar(&buffer, "buffer", "64K", i18n.T("flag.cp_buffer"))
+	fs.Var(&cp.includes, "include", i18n.T("flag.cp_include"))
+	fs.Var(&cp.excludes, "exclude", i18n.T("flag.cp_exclude"))
+	fs.BoolVar(&cp.tar, "tar", false, i18n.T("flag.cp_tar"))
+	fs.StringVar(&cp.compress, "compress", "", i18n.T("flag.cp_compress"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	compress, err := parseCompress(cp.compress)
+	if err != nil {
+		return err
+	}
+	cp.compress = compress
+
+	size, err := utils.ParseSize(buffer)
+	if err != nil || size <= 0 {
+		return errors.New(i18n.T("cp.invalid_buffer", buffer))
+	}
+	cp.buffer = int(size)
+
+	if limit != "" {
+		size, err := utils.ParseSize(limit)
+		if err != nil {
+			return errors.New(i18n.T("cp.invalid_limit", limit))
+		}
+		if size > 0 {
+			cp.limit = newRateLimiter(size)
+		}
+	}
 
-	var args = flag.Args()
+	args = fs.Args()
 	var length = len(args)
-	var err error
 
 	if len(args) < 1 {
-		return errors.New("请输入完整参数")
+		return errors.New(i18n.T("input.incomplete"))
 	}
 
-	cp.target, err = newTransferObject(*cp.cfg, args[length-1])
+	cp.target, err = newObject(args[length-1])
 	if err != nil {
 		return err
 	}
 
 	cp.sources = make([]*TransferObject, 0)
 	for _, arg := range args[:length-1] {
-		s, err := newTransferObject(*cp.cfg, arg)
+		s, err := newObject(arg)
 		if err != nil {
 			return err
 		}
 
 		if s.resType == ResTypeSrc && s.resType == cp.target.resType {
-			return errors.New("源和目标不能同时为本地地址")
+			return errors.New(i18n.T("cp.same_side"))
 		}
 
 		cp.sources = append(cp.sources, s)
@@ -155,51 +294,92 @@ func (cp *Cp) ioCopy(srcIO IOClient, dstIO IOClient, srcFile FileLike, dst strin
 		_ = dstFile.Close()
 	}()
 
-	bytesCount := 0
+	srcFileInfo, err := srcFile.Stat()
+	if err != nil {
+		return srcFile.Name(), err
+	}
+
+	var bytesCount int64
+	total := srcFileInfo.Size()
 	filename := path.Base(srcFile.Name())
 	startTime := time.Now()
-	speed := 0.0
-	var process = 0.0
+	done := make(chan struct{})
+	stopped := make(chan struct{})
 
 	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
 		for {
-			cp.printProcess(filename, process, startTime, speed)
-			time.Sleep(time.Second)
-			if process >= 100 {
-				break
+			cp.printProcess(filename, atomic.LoadInt64(&bytesCount), total, startTime)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
 			}
 		}
 	}()
 
-	srcFileInfo, err := srcFile.Stat()
+	err = cp.copyFile(dstFile, srcFile, &bytesCount)
+	close(done)
+	<-stopped
+
+	cp.printProcess(filename, atomic.LoadInt64(&bytesCount), total, startTime)
+	fmt.Println("")
 	if err != nil {
 		return srcFile.Name(), err
 	}
 
-	bytes := make([]byte, 64*1024)
+	cp.stats.files++
+	cp.stats.bytes += bytesCount
+	return "", nil
+}
+
+// 按缓冲区大小复制，count 记录已写入的字节数
+func (cp *Cp) copyFile(dst io.Writer, src io.Reader, count *int64) error {
+	size := cp.buffer
+	if size <= 0 {
+		size = defaultCpBuffer
+	}
+
+	buffer := make([]byte, size)
 	for {
-		n, err := srcFile.Read(bytes[:])
-		eof := err == io.EOF
-		if err != nil && err != io.EOF {
-			return srcFile.Name(), err
+		n, err := src.Read(buffer)
+		if n > 0 {
+			cp.limit.wait(n)
+
+			wn, werr := dst.Write(buffer[:n])
+			atomic.AddInt64(count, int64(wn))
+			if werr != nil {
+				return werr
+			}
 		}
 
-		wn, err := dstFile.Write(bytes[:n])
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return cp.target.path, err
+			return err
 		}
-		bytesCount += wn
-		process = float64(bytesCount) / float64(srcFileInfo.Size()) * 100
-		speed = float64(bytesCount) / time.Now().Sub(startTime).Seconds()
+	}
+}
 
-		if eof {
-			cp.printProcess(filename, 100.0, startTime, speed)
-			break
-		}
+// 展开通配符，未包含通配符或没有匹配的文件时原样返回，文件名本身包含 [ 等字符时仍可复制
+func expandGlob(client IOClient, pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
 	}
 
-	fmt.Println("")
-	return "", nil
+	files, err := client.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return []string{pattern}, nil
+	}
+
+	return files, nil
 }
 
 // 传输
@@ -222,7 +402,7 @@ func (cp *Cp) transferNew(srcIO IOClient, dstIO IOClient, src string, dst string
 
 	if srcFileInfo.IsDir() {
 		if !cp.isDir {
-			return src, errors.New("是一个目录")
+			return src, errors.New(i18n.T("cp.is_dir"))
 		}
 
 		childFiles, err := srcIO.ReadDir(srcFile.Name())
@@ -237,6 +417,10 @@ func (cp *Cp) transferNew(srcIO IOClient, dstIO IOClient, src string, dst string
 		}
 
 		for _, childFile := range childFiles {
+			if !cp.shouldTransfer(strings.TrimPrefix(path.Join(vPath, childFile.Name()), "/"), childFile.IsDir()) {
+				continue
+			}
+
 			childFilename := path.Join(src, childFile.Name())
 			if str, err := cp.transferNew(srcIO, dstIO, childFilename, dst, vPath); err != nil {
 				cp.printFileError(str, err)
@@ -287,10 +471,28 @@ func (cp *Cp) parseDstFilename(client IOClient, src string, dst string) (string,
 	return dst, nil
 }
 
-func (cp *Cp) printProcess(name string, process float64, startTime time.Time, speed float64) {
-	// TODO 文件大小
+// 显示进度：文件名 已传输/大小 百分比 速度 已用时间 剩余时间
+func (cp *Cp) printProcess(name string, transferred int64, total int64, startTime time.Time) {
 	execTime := time.Now().Sub(startTime)
 
+	process := 100.0
+	if total > 0 {
+		process = float64(transferred) / float64(total) * 100
+	}
+
+	speed := 0.0
+	if seconds := execTime.Seconds(); seconds > 0 {
+		speed = float64(transferred) / seconds
+	}
+
+	extInfo := fmt.Sprintf("%s/%s  %6.2f%%  %10s/s  %s  ETA %s",
+		utils.SizeFormat(float64(transferred)),
+		utils.SizeFormat(float64(total)),
+		process,
+		utils.SizeFormat(speed),
+		formatClock(execTime),
+		formatClock(transferETA(transferred, total, speed)))
+
 	type winSize struct {
 		Row    uint16
 		Col    uint16
@@ -305,47 +507,122 @@ func (cp *Cp) printProcess(name string, process float64, startTime time.Time, sp
 
 	padding := 0
 	if int(retCode) != -1 {
-		padding = int(ws.Col) - utils.ZhLen(name) - 40
+		padding = int(ws.Col) - utils.ZhLen(name) - len(extInfo) - 1
+	}
+	if padding < 1 {
+		padding = 1
 	}
 
-	extInfo := fmt.Sprintf("%.2f%%  %10s/s  %02.0f:%02.0f:%02.0f",
-		process,
-		utils.SizeFormat(speed),
-		execTime.Hours(),
-		execTime.Minutes(),
-		execTime.Seconds())
-
-	format := "\r%s%-" + strconv.Itoa(padding) + "s%40s"
+	format := "\r%s%-" + strconv.Itoa(padding) + "s%s"
 	fmt.Printf(format, name, "", extInfo)
 }
 
+// 剩余时间，速度为0时无法估算，返回0
+func transferETA(transferred int64, total int64, speed float64) time.Duration {
+	if speed <= 0 || transferred >= total {
+		return 0
+	}
+
+	return time.Duration(float64(total-transferred) / speed * float64(time.Second))
+}
+
+// 格式化为 时:分:秒
+func formatClock(d time.Duration) string {
+	seconds := int64(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
 func (cp *Cp) printFileError(name string, err error) {
+	cp.stats.failed++
 	fmt.Println(name, ": ", err)
 }
 
+// 输出传输统计：文件数、总大小、平均速度、失败数
+func (cp *Cp) printSummary(elapsed time.Duration) {
+	speed := 0.0
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		speed = float64(cp.stats.bytes) / seconds
+	}
+
+	msg := i18n.T("cp.summary",
+		cp.stats.files,
+		utils.SizeFormat(float64(cp.stats.bytes)),
+		utils.SizeFormat(speed),
+		formatClock(elapsed),
+		cp.stats.failed)
+
+	if cp.stats.failed > 0 {
+		utils.Errorln(msg)
+	} else {
+		utils.Infoln(msg)
+	}
+}
+
 // 创建传输对象
+// 格式为 服务器:路径，服务器可为编号、别名或IP，IPv6 地址需使用 [::1]:/path 形式，路径中可包含冒号
+// 不含冒号、冒号前包含 / 或为 Windows 盘符（如 C:\data）时为本地路径
 func newTransferObject(cfg Config, raw string) (*TransferObject, error) {
 	obj := TransferObject{
-		raw: raw,
-	}
-
-	args := strings.Split(raw, ":")
-	switch len(args) {
-	case 1:
-		obj.resType = ResTypeSrc
-		obj.path = args[0]
-	case 2:
-		obj.path = strings.TrimSpace(args[1])
-		serverIndex, exists := cfg.serverIndex[args[0]]
-		if !exists {
-			return nil, errors.New("服务器" + args[0] + "不存在")
-		}
-		obj.resType = ResTypeDst
-		obj.server = serverIndex.server
+		raw:     raw,
+		resType: ResTypeSrc,
+		path:    raw,
+	}
+
+	host, remotePath, ok := splitRemotePath(raw)
+	if !ok {
+		return &obj, nil
+	}
 
-	default:
-		return nil, errors.New(raw + " 格式错误")
+	server, err := cfg.lookupServer(host)
+	if isDrivePath(raw) && (runtime.GOOS == "windows" || err != nil) {
+		return &obj, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	obj.resType = ResTypeDst
+	obj.server = server
+	obj.path = remotePath
 	return &obj, nil
 }
+
+// 拆分 服务器:路径，不是远程路径时返回 false
+func splitRemotePath(raw string) (string, string, bool) {
+	if strings.HasPrefix(raw, "[") {
+		end := strings.Index(raw, "]")
+		if end == -1 || !strings.HasPrefix(raw[end+1:], ":") {
+			return "", "", false
+		}
+		return raw[1:end], remotePathOrHome(raw[end+2:]), true
+	}
+
+	i := strings.Index(raw, ":")
+	if i <= 0 || strings.ContainsAny(raw[:i], `/\`) {
+		return "", "", false
+	}
+
+	return raw[:i], remotePathOrHome(raw[i+1:]), true
+}
+
+// 路径为空时使用远程用户的主目录
+func remotePathOrHome(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// Windows 盘符路径，如 C:\data、D:/data、C:
+func isDrivePath(raw string) bool {
+	if len(raw) < 2 || raw[1] != ':' {
+		return false
+	}
+	c := raw[0]
+	if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+		return false
+	}
+
+	return len(raw) == 2 || raw[2] == '\\' || raw[2] == '/'
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	var slept time.Duration
+	limiter := &rateLimiter{
+		limit: 1000,
+		last:  now,
+		now:   func() time.Time { return now },
+		sleep: func(d time.Duration) {
+			slept += d
+			now = now.Add(d)
+		},
+	}
+
+	// 3000字节需要3秒
+	for i := 0; i < 30; i++ {
+		limiter.wait(100)
+	}
+	if slept != 3*time.Second {
+		t.Errorf("slept %v, want 3s", slept)
+	}
+
+	// 空闲期间最多积累1秒的流量
+	now = now.Add(10 * time.Second)
+	slept = 0
+	limiter.wait(1500)
+	if slept != 500*time.Millisecond {
+		t.Errorf("slept %v after idle, want 500ms", slept)
+	}
+
+	var unlimited *rateLimiter
+	unlimited.wait(100)
+}
+
+func TestCpCopyFile(t *testing.T) {
+	cp := Cp{}
+	if err := cp.parseWith([]string{"-buffer", "4", "-limit", "0", "a", "b"}, func(raw string) (*TransferObject, error) {
+		return &TransferObject{raw: raw, resType: ResTypeDst}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if cp.buffer != 4 || cp.limit != nil {
+		t.Errorf("buffer = %d limit = %v", cp.buffer, cp.limit)
+	}
+
+	var dst bytes.Buffer
+	var count int64
+	if err := cp.copyFile(&dst, strings.NewReader("hello world"), &count); err != nil {
+		t.Fatal(err)
+	}
+	if dst.String() != "hello world" || count != 11 {
+		t.Errorf("copied %q count %d", dst.String(), count)
+	}
+
+	for _, args := range [][]string{{"-limit", "x", "a", "b"}, {"-buffer", "0", "a", "b"}} {
+		if err := (&Cp{}).parseWith(args, nil); err == nil {
+			t.Errorf("parse %v should fail", args)
+		}
+	}
+}
+
+func TestTransferETA(t *testing.T) {
+	if eta := transferETA(250, 1000, 50); eta != 15*time.Second {
+		t.Errorf("eta = %v", eta)
+	}
+	if eta := transferETA(0, 1000, 0); eta != 0 {
+		t.Errorf("eta without speed = %v", eta)
+	}
+
+	if s := formatClock(3*time.Hour + 25*time.Minute + 7*time.Second); s != "03:25:07" {
+		t.Errorf("formatClock = %s", s)
+	}
+}
+
+func TestNewTransferObject(t *testing.T) {
+	var cfg Config
+	raw := `{"servers": [
+		{"name": "web", "ip": "10.0.0.1", "alias": "web"},
+		{"name": "v6", "ip": "fe80::1"},
+		{"name": "c1", "ip": "10.0.0.3", "alias": "c"},
+		{"name": "d1", "ip": "10.0.0.5"},
+		{"name": "d2", "ip": "10.0.0.5"}
+	]}`
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		raw    string
+		server string
+		path   string
+	}{
+		{"/var/log/a.log", "", "/var/log/a.log"},
+		{"./a:b", "", "./a:b"},
+		{"web:/var/log/*.gz", "web", "/var/log/*.gz"},
+		{"1:/tmp/a:b", "web", "/tmp/a:b"},
+		{"10.0.0.1:/tmp", "web", "/tmp"},
+		{"[fe80::1]:/tmp", "v6", "/tmp"},
+		{"web:", "web", "."},
+		{"D:\\data", "", "D:\\data"},
+	}
+	if runtime.GOOS != "windows" {
+		// 与服务器别名相同时优先作为服务器
+		cases = append(cases, struct{ raw, server, path string }{"c:/tmp", "c1", "/tmp"})
+	}
+
+	for _, c := range cases {
+		obj, err := newTransferObject(cfg, c.raw)
+		if err != nil {
+			t.Errorf("%s: %v", c.raw, err)
+			continue
+		}
+
+		name := ""
+		if obj.server != nil {
+			name = obj.server.Name
+		}
+		if name != c.server || obj.path != c.path {
+			t.Errorf("%s: got %s %s, want %s %s", c.raw, name, obj.path, c.server, c.path)
+		}
+	}
+
+	for _, raw := range []string{"nope:/tmp", "10.0.0.5:/tmp", "[::2]:/tmp"} {
+		if _, err := newTransferObject(cfg, raw); err == nil {
+			t.Errorf("%s should fail", raw)
+		}
+	}
+}
+
+func TestCpPatterns(t *testing.T) {
+	src, err := ioutil.TempDir("", "autossh-cp-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "autossh-cp-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	files := []string{"a.go", "a.log", "docs/b.go", "docs/c.md", ".git/config", "vendor/x.go"}
+	for _, file := range files {
+		file = filepath.Join(src, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cp := Cp{}
+	args := []string{"-r", "-exclude", ".git", "-exclude", "/vendor", "-include", "*.go", "-include", "docs/*.md", "a", "b"}
+	if err := cp.parseWith(args, func(raw string) (*TransferObject, error) {
+		return &TransferObject{raw: raw, resType: ResTypeDst}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	local := new(LocalIOClient)
+	if file, err := cp.transferNew(local, local, src, dst, ""); err != nil {
+		t.Fatal(file, err)
+	}
+
+	copied := make([]string, 0)
+	_ = filepath.Walk(dst, func(file string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dst, file)
+			copied = append(copied, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(copied)
+	if strings.Join(copied, ",") != "a.go,docs/b.go,docs/c.md" {
+		t.Errorf("copied %v", copied)
+	}
+
+	matches, err := expandGlob(local, filepath.Join(src, "a.*"))
+	if err != nil || len(matches) != 2 {
+		t.Errorf("expandGlob = %v %v", matches, err)
+	}
+
+	if err := (&Cp{}).parseWith([]string{"-exclude", "[", "a", "b"}, nil); err == nil {
+		t.Error("invalid pattern should fail")
+	}
+}
@@ -0,0 +1,372 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	ExportFormatIni  = "ini"
+	ExportFormatYaml = "yaml"
+
+	ansibleUngrouped = "ungrouped"
+)
+
+// Ansible 清单
+type ansibleInventory struct {
+	groups   []string            // 组名，按配置顺序
+	roots    []string            // 顶级组名
+	hosts    map[string][]string // 组名 => 主机名
+	children map[string][]string // 组名 => 子组名
+	hostvars map[string]map[string]interface{}
+}
+
+// 导出配置
+// export ansible [-format ini|yaml] [-list] [-host name] [targets]
+// -list、-host 为 Ansible 动态清单模式
+func showExport(configFile string) {
+	args := flag.Args()[1:]
+	if len(args) == 0 {
+		utils.Errorln(i18n.T("export.type_required"))
+		return
+	}
+
+	if args[0] != "ansible" {
+		utils.Errorln(i18n.T("export.invalid_type", args[0]))
+		return
+	}
+
+	var format, host string
+	var list bool
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.StringVar(&format, "format", ExportFormatIni, i18n.T("flag.export_format"))
+	fs.BoolVar(&list, "list", false, i18n.T("flag.export_list"))
+	fs.StringVar(&host, "host", "", i18n.T("flag.export_host"))
+	if err := fs.Parse(args[1:]); err != nil {
+		return
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	targets := fs.Args()
+	if len(targets) == 0 {
+		targets = []string{"all"}
+	}
+
+	inventory, err := cfg.ansibleInventory(targets)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	switch {
+	case list:
+		err = inventory.writeJson(os.Stdout)
+	case host != "":
+		err = inventory.writeHostJson(os.Stdout, host)
+	case format == ExportFormatIni:
+		err = inventory.writeIni(os.Stdout)
+	case format == ExportFormatYaml:
+		err = inventory.writeYaml(os.Stdout)
+	default:
+		err = errors.New(i18n.T("list.invalid_format", format))
+	}
+
+	if err != nil {
+		utils.Errorln(err)
+	}
+}
+
+// 生成 Ansible 清单，组名取自 GroupName，未分组的服务器归入 ungrouped
+// 主机名优先使用别名，否则使用编号；不导出密码
+func (cfg *Config) ansibleInventory(targets []string) (*ansibleInventory, error) {
+	indexes, err := cfg.resolveTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &ansibleInventory{
+		hosts:    make(map[string][]string),
+		children: make(map[string][]string),
+		hostvars: make(map[string]map[string]interface{}),
+	}
+
+	for _, index := range indexes {
+		server := cfg.serverIndex[index].server
+
+		group := ansibleUngrouped
+		if server.group != nil {
+			group = ansibleGroupName(server.group)
+		}
+		inventory.addGroup(server.group)
+
+		name := index
+		if server.Alias != "" {
+			name = server.Alias
+		}
+
+		vars, err := server.ansibleVars(index)
+		if err != nil {
+			return nil, err
+		}
+
+		inventory.hosts[group] = append(inventory.hosts[group], name)
+		inventory.hostvars[name] = vars
+	}
+
+	return inventory, nil
+}
+
+// 添加组，子组同时添加上级组并记录层级关系，group 为 nil 时为 ungrouped
+func (inventory *ansibleInventory) addGroup(group *Group) {
+	name := ansibleUngrouped
+	if group != nil {
+		name = ansibleGroupName(group)
+	}
+	if _, ok := inventory.hosts[name]; ok {
+		return
+	}
+
+	inventory.groups = append(inventory.groups, name)
+	inventory.hosts[name] = nil
+	if group == nil || group.parent == nil {
+		inventory.roots = append(inventory.roots, name)
+		return
+	}
+
+	inventory.addGroup(group.parent)
+	parent := ansibleGroupName(group.parent)
+	inventory.children[parent] = append(inventory.children[parent], name)
+}
+
+// 组名只保留字母、数字及下划线，子组以 _ 连接上级组名
+func ansibleGroupName(group *Group) string {
+	name := group.GroupName
+	if name == "" {
+		name = group.Prefix
+	}
+	if group.parent != nil {
+		name = ansibleGroupName(group.parent) + "_" + name
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "group_" + name
+	}
+
+	return name
+}
+
+// 主机连接变量
+func (server *Server) ansibleVars(index string) (map[string]interface{}, error) {
+	vars := map[string]interface{}{
+		"ansible_host":  server.Ip,
+		"ansible_port":  server.Port,
+		"ansible_user":  server.User,
+		"autossh_index": index,
+		"autossh_name":  server.Name,
+	}
+
+	if strings.ToLower(server.Method) == "key" && server.Key != "" {
+		vars["ansible_ssh_private_key_file"] = server.Key
+	}
+
+	command, p, err := server.effectiveProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	if command == "" && p != nil {
+		// nc 不支持代理认证，忽略认证信息会导致连接失败
+		if p.User != "" || p.Password != "" {
+			return nil, errors.New(i18n.T("export.proxy_auth", server.Name))
+		}
+		command = proxyNcCommand(p)
+	}
+	if command != "" {
+		vars["ansible_ssh_common_args"] = "-o ProxyCommand=" + utils.ShellQuote(command)
+	}
+
+	return vars, nil
+}
+
+// 将代理转换为 OpenSSH 可用的 nc 命令，HTTPS 代理无对应命令
+func proxyNcCommand(p *Proxy) string {
+	addr := p.Server + ":" + strconv.Itoa(p.Port)
+
+	switch ProxyType(strings.ToUpper(string(p.Type))) {
+	case ProxyTypeSocks5:
+		return "nc -X 5 -x " + addr + " %h %p"
+	case ProxyTypeSocks4, ProxyTypeSocks4A:
+		return "nc -X 4 -x " + addr + " %h %p"
+	case ProxyTypeHttp:
+		return "nc -X connect -x " + addr + " %h %p"
+	default:
+		return ""
+	}
+}
+
+// 动态清单 --list 输出
+func (inventory *ansibleInventory) writeJson(w io.Writer) error {
+	data := make(map[string]interface{})
+	for _, group := range inventory.groups {
+		item := make(map[string]interface{})
+		if hosts := inventory.hosts[group]; len(hosts) > 0 {
+			item["hosts"] = hosts
+		}
+		if children := inventory.children[group]; len(children) > 0 {
+			item["children"] = children
+		}
+		data[group] = item
+	}
+	data["_meta"] = map[string]interface{}{"hostvars": inventory.hostvars}
+
+	return writeIndentJson(w, data)
+}
+
+// 动态清单 --host 输出
+func (inventory *ansibleInventory) writeHostJson(w io.Writer, host string) error {
+	vars, ok := inventory.hostvars[host]
+	if !ok {
+		vars = make(map[string]interface{})
+	}
+
+	return writeIndentJson(w, vars)
+}
+
+func writeIndentJson(w io.Writer, data interface{}) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func (inventory *ansibleInventory) writeIni(w io.Writer) error {
+	sections := 0
+	section := func(title string) {
+		if sections > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		sections++
+		_, _ = fmt.Fprintln(w, "["+title+"]")
+	}
+
+	for _, group := range inventory.groups {
+		if len(inventory.hosts[group]) == 0 {
+			continue
+		}
+		section(group)
+
+		for _, host := range inventory.hosts[group] {
+			line := host
+			vars := inventory.hostvars[host]
+			for _, key := range sortedKeys(vars) {
+				value := fmt.Sprint(vars[key])
+				if value == "" || strings.ContainsAny(value, " \t'\"=#;") {
+					value = utils.ShellQuote(value)
+				}
+				line += " " + key + "=" + value
+			}
+
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, group := range inventory.groups {
+		if len(inventory.children[group]) == 0 {
+			continue
+		}
+		section(group + ":children")
+
+		for _, child := range inventory.children[group] {
+			if _, err := fmt.Fprintln(w, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (inventory *ansibleInventory) writeYaml(w io.Writer) error {
+	_, _ = fmt.Fprintln(w, "all:")
+	_, _ = fmt.Fprintln(w, "  children:")
+
+	for _, group := range inventory.roots {
+		if err := inventory.writeYamlGroup(w, group, "    "); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 输出组及其子组，indent 为组名的缩进
+func (inventory *ansibleInventory) writeYamlGroup(w io.Writer, group string, indent string) error {
+	_, _ = fmt.Fprintln(w, indent+group+":")
+
+	if hosts := inventory.hosts[group]; len(hosts) > 0 {
+		_, _ = fmt.Fprintln(w, indent+"  hosts:")
+		for _, host := range hosts {
+			_, _ = fmt.Fprintln(w, indent+"    "+strconv.Quote(host)+":")
+
+			vars := inventory.hostvars[host]
+			for _, key := range sortedKeys(vars) {
+				value := vars[key]
+				if str, ok := value.(string); ok {
+					value = strconv.Quote(str)
+				}
+
+				if _, err := fmt.Fprintf(w, "%s      %s: %v\n", indent, key, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if children := inventory.children[group]; len(children) > 0 {
+		_, _ = fmt.Fprintln(w, indent+"  children:")
+		for _, child := range children {
+			if err := inventory.writeYamlGroup(w, child, indent+"    "); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnsibleInventory(t *testing.T) {
+	raw := `{
+		"servers": [
+			{"name": "web", "ip": "10.0.0.1", "user": "root", "password": "top-secret", "alias": "web"}
+		],
+		"groups": [
+			{"group_name": "db servers", "prefix": "d", "proxy": {"type": "SOCKS5", "server": "bastion", "port": 1080}, "servers": [
+				{"name": "mysql", "ip": "10.0.0.2", "port": 2222, "user": "admin", "method": "key", "key": "~/.ssh/id_rsa", "password": "key-secret"}
+			]}
+		]
+	}`
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	inventory, err := cfg.ansibleInventory([]string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := inventory.writeIni(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `[ungrouped]
+web ansible_host=10.0.0.1 ansible_port=22 ansible_user=root autossh_index=1 autossh_name=web
+
+[db_servers]
+d1 ansible_host=10.0.0.2 ansible_port=2222 ansible_ssh_common_args='-o ProxyCommand='\''nc -X 5 -x bastion:1080 %h %p'\''' ansible_ssh_private_key_file=~/.ssh/id_rsa ansible_user=admin autossh_index=d1 autossh_name=mysql
+`
+	if out.String() != want {
+		t.Errorf("ini:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := inventory.writeJson(&out); err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		DbServers struct {
+			Hosts []string `json:"hosts"`
+		} `json:"db_servers"`
+		Meta struct {
+			Hostvars map[string]map[string]interface{} `json:"hostvars"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.DbServers.Hosts) != 1 || list.Meta.Hostvars["d1"]["ansible_port"] != float64(2222) {
+		t.Errorf("list: %s", out.String())
+	}
+
+	out.Reset()
+	if err := inventory.writeYaml(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "    db_servers:\n      hosts:\n        \"d1\":\n") {
+		t.Errorf("yaml:\n%s", out.String())
+	}
+
+	if strings.Contains(out.String(), "secret") {
+		t.Error("inventory contains secret")
+	}
+
+	// 代理认证信息无法转换为 nc 命令
+	cfg.Groups[0].Proxy.User = "proxy"
+	cfg.Groups[0].Proxy.Password = "proxy-secret"
+	if _, err := cfg.ansibleInventory([]string{"d"}); err == nil {
+		t.Error("expected error for proxy with credentials")
+	}
+}
+
+func TestAnsibleInventoryChildren(t *testing.T) {
+	var cfg Config
+	raw := `{"groups": [{"group_name": "cn", "prefix": "c", "groups": [
+		{"group_name": "prod", "prefix": "p", "servers": [{"name": "web", "ip": "10.0.1.1", "user": "root"}]}
+	]}]}`
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	inventory, err := cfg.ansibleInventory([]string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := inventory.writeIni(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `[cn_prod]
+c.p.1 ansible_host=10.0.1.1 ansible_port=22 ansible_user=root autossh_index=c.p.1 autossh_name=web
+
+[cn:children]
+cn_prod
+`
+	if out.String() != want {
+		t.Errorf("ini:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := inventory.writeYaml(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "    cn:\n      children:\n        cn_prod:\n          hosts:\n            \"c.p.1\":\n") {
+		t.Errorf("yaml:\n%s", out.String())
+	}
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"flag"
+	"golang.org/x/crypto/ssh"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultKeyTimeout = 10
+
+// 密钥管理
+// key gen [-key path] [-comment comment]
+// key install [-key path] [-timeout 10] targets
+// key rotate [-key path] [-old path] [-timeout 10] targets
+func showKey(configFile string) {
+	var err error
+	if args := flag.Args()[1:]; len(args) > 0 && args[0] == "gen" {
+		// 生成密钥不需要读取配置
+		err = keyCmd(nil, args[0], args[1:])
+	} else {
+		err = runManageCmd(configFile, keyCmd)
+	}
+
+	if err != nil {
+		utils.Errorln(err)
+	}
+}
+
+func keyCmd(cfg *Config, operation string, args []string) error {
+	var keyFile, oldKey, comment string
+	var timeout int
+	fs := flag.NewFlagSet("key "+operation, flag.ContinueOnError)
+	fs.StringVar(&keyFile, "key", defaultKeyFile, i18n.T("flag.key_file"))
+
+	switch operation {
+	case "gen":
+		fs.StringVar(&comment, "comment", defaultKeyComment(), i18n.T("flag.key_comment"))
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		pub, err := generateKey(keyFile, comment)
+		if err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("key.generated", keyFile, ssh.FingerprintSHA256(pub)))
+		return nil
+	case "install", "rotate":
+		fs.IntVar(&timeout, "timeout", defaultKeyTimeout, i18n.T("flag.key_timeout"))
+		if operation == "rotate" {
+			fs.StringVar(&oldKey, "old", "", i18n.T("flag.key_old"))
+		}
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return errors.New(i18n.T("key.no_targets"))
+		}
+
+		indexes, err := cfg.resolveTargets(fs.Args())
+		if err != nil {
+			return err
+		}
+
+		pub, err := ensureKey(keyFile)
+		if err != nil {
+			return err
+		}
+
+		installer := &keyInstaller{
+			cfg:     cfg,
+			keyFile: keyFile,
+			pub:     pub,
+			timeout: time.Duration(timeout) * time.Second,
+			rotate:  operation == "rotate",
+			oldKey:  oldKey,
+		}
+		return installer.run(indexes)
+	default:
+		return errors.New(i18n.T("manage.unknown_operation", operation))
+	}
+}
+
+func defaultKeyComment() string {
+	hostname, _ := os.Hostname()
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "autossh"
+	}
+
+	return user + "@" + hostname
+}
+
+// 读取公钥，密钥不存在时自动生成
+func ensureKey(keyFile string) (ssh.PublicKey, error) {
+	file, err := utils.ParsePath(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists, _ := utils.FileIsExists(file); exists {
+		return loadPublicKey(file)
+	}
+
+	pub, err := generateKey(file, defaultKeyComment())
+	if err != nil {
+		return nil, err
+	}
+	utils.Infoln(i18n.T("key.generated", keyFile, ssh.FingerprintSHA256(pub)))
+
+	return pub, nil
+}
+
+// 安装公钥到服务器并切换为密钥认证
+type keyInstaller struct {
+	cfg     *Config
+	keyFile string
+	pub     ssh.PublicKey
+	timeout time.Duration
+	rotate  bool   // 安装后删除旧密钥
+	oldKey  string // 旧密钥路径，为空时使用服务器当前的密钥
+}
+
+func (installer *keyInstaller) run(indexes []string) error {
+	failed := 0
+	for _, index := range indexes {
+		if err := installer.install(index); err != nil {
+			failed++
+			utils.Errorln("[" + index + "] " + err.Error())
+			continue
+		}
+
+		utils.Infoln("[" + index + "] " + i18n.T("key.installed"))
+	}
+
+	// 部分失败时仍保存已成功的服务器
+	if failed < len(indexes) {
+		if err := installer.cfg.saveConfig(true); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return errors.New(i18n.T("key.failed", failed, len(indexes)))
+	}
+
+	return nil
+}
+
+// 使用当前的认证方式登录并安装公钥，使用新密钥登录成功后再修改配置
+func (installer *keyInstaller) install(index string) error {
+	server := installer.cfg.serverIndex[index].server
+
+	var old ssh.PublicKey
+	if installer.rotate {
+		oldKey := installer.oldKey
+		if oldKey == "" {
+			if strings.ToLower(server.Method) != "key" {
+				return errors.New(i18n.T("key.no_old_key"))
+			}
+			oldKey = server.keyFile()
+		}
+
+		var err error
+		if old, err = loadPublicKey(oldKey); err != nil {
+			return err
+		}
+	}
+
+	client, err := server.withTimeout(installer.timeout).GetSshClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := runRemote(client, authorizedKeysAppendCmd(installer.pub)); err != nil {
+		return err
+	}
+
+	// 使用新密钥登录验证
+	keyServer := installer.keyServer(server)
+	verify, err := keyServer.withTimeout(installer.timeout).GetSshClient()
+	if err != nil {
+		return errors.New(i18n.T("key.verify_failed", err))
+	}
+	defer verify.Close()
+
+	if old != nil && string(old.Marshal()) != string(installer.pub.Marshal()) {
+		if err := runRemote(verify, authorizedKeysRemoveCmd(old)); err != nil {
+			return err
+		}
+	}
+
+	return installer.cfg.updateServer(index, func(s *Server) {
+		s.Method = keyServer.Method
+		s.Key = keyServer.Key
+		s.Cert = ""
+		s.Password = ""
+		s.PasswordSource = ""
+	})
+}
+
+// 使用新密钥认证的服务器配置
+func (installer *keyInstaller) keyServer(server *Server) *Server {
+	s := *server
+	s.Method = "key"
+	s.Key = installer.keyFile
+	s.Cert = ""
+	s.Password = ""
+	s.PasswordSource = ""
+	s.secretResolved = false
+
+	return &s
+}
+
+// 公钥在 authorized_keys 中的匹配内容，不含备注
+func authorizedKeyLine(pub ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+}
+
+// 追加公钥，已存在时不重复添加
+func authorizedKeysAppendCmd(pub ssh.PublicKey) string {
+	key := utils.ShellQuote(authorizedKeyLine(pub))
+	return "umask 077; mkdir -p ~/.ssh && touch ~/.ssh/authorized_keys && " +
+		"(grep -qF " + key + " ~/.ssh/authorized_keys || echo " + key + " >> ~/.ssh/authorized_keys)"
+}
+
+// 删除公钥
+func authorizedKeysRemoveCmd(pub ssh.PublicKey) string {
+	key := utils.ShellQuote(authorizedKeyLine(pub))
+	return "f=~/.ssh/authorized_keys; grep -vF " + key + " \"$f\" > \"$f.autossh\"; cat \"$f.autossh\" > \"$f\" && rm -f \"$f.autossh\""
+}
+
+// 执行远程命令，失败时返回命令输出
+func runRemote(client *ssh.Client, cmd string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if output, err := session.CombinedOutput(cmd); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	ListFormatTable = "table"
+	ListFormatJson  = "json"
+	ListFormatCsv   = "csv"
+)
+
+// 服务器信息，用于输出清单，不包含密码等敏感信息
+type ServerInfo struct {
+	Index        string                 `json:"index"`
+	Alias        string                 `json:"alias"`
+	Name         string                 `json:"name"`
+	Group        string                 `json:"group"`
+	Host         string                 `json:"host"`
+	Port         int                    `json:"port"`
+	User         string                 `json:"user"`
+	Method       string                 `json:"method"`
+	Proxy        string                 `json:"proxy,omitempty"`         // 生效的代理，不含认证信息
+	ProxyCommand string                 `json:"proxy_command,omitempty"` // 生效的代理命令
+	Options      map[string]interface{} `json:"options"`                 // 合并全局配置后的选项
+}
+
+// 输出服务器清单
+// list [-format table|json|csv] [targets]
+func showList(configFile string) {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	var format string
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.StringVar(&format, "format", ListFormatTable, i18n.T("flag.list_format"))
+	if err := fs.Parse(flag.Args()[1:]); err != nil {
+		return
+	}
+
+	targets := fs.Args()
+	if len(targets) == 0 {
+		targets = []string{"all"}
+	}
+
+	infos, err := cfg.serverInfos(targets)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	if err := writeServerInfos(os.Stdout, infos, format); err != nil {
+		utils.Errorln(err)
+	}
+}
+
+// 获取目标服务器的信息
+func (cfg *Config) serverInfos(targets []string) ([]ServerInfo, error) {
+	indexes, err := cfg.resolveTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ServerInfo, 0, len(indexes))
+	for _, index := range indexes {
+		info, err := cfg.serverIndex[index].server.info(index)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (server *Server) info(index string) (ServerInfo, error) {
+	command, p, err := server.effectiveProxy()
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	info := ServerInfo{
+		Index:        index,
+		Alias:        server.Alias,
+		Name:         server.Name,
+		Group:        server.groupName,
+		Host:         server.Ip,
+		Port:         server.Port,
+		User:         server.User,
+		Method:       server.Method,
+		ProxyCommand: command,
+		Options:      server.Options,
+	}
+	if info.Options == nil {
+		info.Options = make(map[string]interface{})
+	}
+	if p != nil {
+		info.Proxy = strings.ToLower(string(p.Type)) + "://" + p.Server + ":" + strconv.Itoa(p.Port)
+	}
+
+	return info, nil
+}
+
+// 按指定格式输出服务器信息
+func writeServerInfos(w io.Writer, infos []ServerInfo, format string) error {
+	switch format {
+	case ListFormatJson:
+		b, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case ListFormatCsv:
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"index", "alias", "name", "group", "host", "port", "user", "method", "proxy", "proxy_command", "options"})
+		for _, info := range infos {
+			_ = writer.Write(append(info.columns(), info.ProxyCommand, formatOptions(info.Options)))
+		}
+		writer.Flush()
+		return writer.Error()
+	case ListFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, i18n.T("list.header"))
+		for _, info := range infos {
+			columns := info.columns()
+			if info.ProxyCommand != "" {
+				columns[len(columns)-1] = info.ProxyCommand
+			}
+			for i := range columns {
+				columns[i] = orDash(columns[i])
+			}
+			_, _ = fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return errors.New(i18n.T("list.invalid_format", format))
+	}
+}
+
+func (info ServerInfo) columns() []string {
+	return []string{info.Index, info.Alias, info.Name, info.Group, info.Host, strconv.Itoa(info.Port), info.User, info.Method, info.Proxy}
+}
+
+// 选项格式化为 key=value，以分号分隔并按key排序
+func formatOptions(options map[string]interface{}) string {
+	pairs := make([]string, 0, len(options))
+	for _, k := range sortedKeys(options) {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, options[k]))
+	}
+
+	return strings.Join(pairs, ";")
+}
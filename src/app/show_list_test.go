@@ -0,0 +1,58 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteServerInfos(t *testing.T) {
+	raw := `{
+		"options": {"ServerAliveInterval": 30},
+		"servers": [
+			{"name": "web", "ip": "10.0.0.1", "user": "root", "password": "top-secret", "alias": "w"}
+		],
+		"groups": [
+			{"group_name": "db", "prefix": "d", "proxy": {"type": "SOCKS5", "server": "bastion", "port": 1080, "user": "u", "password": "proxy-secret"}, "servers": [
+				{"name": "mysql", "ip": "10.0.0.2", "port": 2222, "user": "admin", "method": "key", "key": "~/.ssh/id_rsa", "password": "key-secret"}
+			]}
+		]
+	}`
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := cfg.serverInfos([]string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[1].Group != "db" || infos[1].Port != 2222 || infos[1].Proxy != "socks5://bastion:1080" {
+		t.Fatalf("infos = %+v", infos)
+	}
+	if infos[0].Options["ServerAliveInterval"] != float64(30) {
+		t.Errorf("options not merged: %+v", infos[0].Options)
+	}
+
+	for _, format := range []string{ListFormatJson, ListFormatCsv, ListFormatTable} {
+		var out bytes.Buffer
+		if err := writeServerInfos(&out, infos, format); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), "secret") {
+			t.Errorf("%s output contains secret: %s", format, out.String())
+		}
+		if !strings.Contains(out.String(), "10.0.0.2") {
+			t.Errorf("%s output missing host: %s", format, out.String())
+		}
+	}
+
+	if err := writeServerInfos(&bytes.Buffer{}, infos, "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
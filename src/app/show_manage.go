@@ -0,0 +1,316 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// 服务器参数
+type serverFlags struct {
+	name   string
+	ip     string
+	port   int
+	user   string
+	source string
+	method string
+	key    string
+	cert   string
+	alias  string
+	group  string
+}
+
+func (f *serverFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.name, "name", "", i18n.T("flag.name"))
+	fs.StringVar(&f.ip, "ip", "", i18n.T("flag.ip"))
+	fs.IntVar(&f.port, "port", 22, i18n.T("flag.port"))
+	fs.StringVar(&f.user, "user", "root", i18n.T("flag.user"))
+	fs.StringVar(&f.source, "password-source", "", i18n.T("flag.password_source"))
+	fs.StringVar(&f.method, "method", "password", i18n.T("flag.method"))
+	fs.StringVar(&f.key, "key", "", i18n.T("flag.key"))
+	fs.StringVar(&f.cert, "cert", "", i18n.T("flag.cert"))
+	fs.StringVar(&f.alias, "alias", "", i18n.T("flag.alias"))
+}
+
+// 将命令行中指定的参数设置到服务器
+func (f *serverFlags) apply(fs *flag.FlagSet, server *Server) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			server.Name = f.name
+		case "ip":
+			server.Ip = f.ip
+		case "port":
+			server.Port = f.port
+		case "user":
+			server.User = f.user
+		case "password-source":
+			server.PasswordSource = f.source
+		case "method":
+			server.Method = f.method
+		case "key":
+			server.Key = f.key
+		case "cert":
+			server.Cert = f.cert
+		case "alias":
+			server.Alias = f.alias
+		}
+	})
+}
+
+// 服务器管理
+// server add -name name -ip ip [-port 22] [-user root] [-password-source source] [-method password|key] [-key path] [-cert path] [-alias alias] [-group prefix]
+// server edit id [-name ...]
+// server rm id
+// server move id [-group prefix | -ungroup] [-position n]
+func showServerCmd(configFile string) {
+	if err := runManageCmd(configFile, serverCmd); err != nil {
+		utils.Errorln(err)
+	}
+}
+
+// 组管理
+// group add -name name -prefix prefix [-parent prefix]
+// group rename prefix [-name name] [-prefix prefix]
+// group rm prefix [-force | -ungroup | -move-to prefix]
+// group move prefix position
+func showGroupCmd(configFile string) {
+	if err := runManageCmd(configFile, groupCmd); err != nil {
+		utils.Errorln(err)
+	}
+}
+
+func runManageCmd(configFile string, handler func(cfg *Config, operation string, args []string) error) error {
+	args := flag.Args()[1:]
+	if len(args) == 0 {
+		return errors.New(i18n.T("input.incomplete"))
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	return handler(cfg, args[0], args[1:])
+}
+
+// 取出第一个位置参数，其余部分作为选项解析
+func parseWithId(fs *flag.FlagSet, args []string) (string, error) {
+	id := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id = args[0]
+		args = args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if id == "" && fs.NArg() > 0 {
+		id = fs.Arg(0)
+	}
+
+	if id == "" {
+		return "", errors.New(i18n.T("input.index_required"))
+	}
+
+	return id, nil
+}
+
+func serverCmd(cfg *Config, operation string, args []string) error {
+	fs := flag.NewFlagSet("server "+operation, flag.ContinueOnError)
+	var f serverFlags
+
+	switch operation {
+	case "add":
+		f.register(fs)
+		fs.StringVar(&f.group, "group", "", i18n.T("flag.add_group"))
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		server := Server{}
+		f.apply(fs, &server)
+		if server.User == "" {
+			server.User = f.user
+		}
+
+		index, err := cfg.addServer(server, f.group)
+		if err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("server.added", index))
+	case "edit":
+		f.register(fs)
+		id, err := parseWithId(fs, args)
+		if err != nil {
+			return err
+		}
+		if fs.NFlag() == 0 {
+			return errors.New(i18n.T("server.edit_nothing"))
+		}
+
+		if err := cfg.updateServer(id, func(server *Server) { f.apply(fs, server) }); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("server.edited", id))
+	case "rm":
+		id, err := parseWithId(fs, args)
+		if err != nil {
+			return err
+		}
+
+		if err := cfg.removeServer(id); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("server.removed", id))
+	case "move":
+		var ungroup bool
+		var position int
+		fs.StringVar(&f.group, "group", "", i18n.T("flag.move_group"))
+		fs.BoolVar(&ungroup, "ungroup", false, i18n.T("flag.ungroup"))
+		fs.IntVar(&position, "position", 0, i18n.T("flag.position"))
+		id, err := parseWithId(fs, args)
+		if err != nil {
+			return err
+		}
+
+		serverIndex, err := cfg.findServer(id)
+		if err != nil {
+			return err
+		}
+
+		target := serverIndex.server.group
+		if ungroup {
+			target = nil
+		} else if f.group != "" {
+			if target = cfg.findGroup(f.group); target == nil {
+				return errors.New(i18n.T("group.not_found", f.group))
+			}
+		}
+
+		index, err := cfg.moveServer(id, target, position)
+		if err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("move.new_index", index))
+	default:
+		return errors.New(i18n.T("manage.unknown_operation", operation))
+	}
+
+	return nil
+}
+
+func groupCmd(cfg *Config, operation string, args []string) error {
+	fs := flag.NewFlagSet("group "+operation, flag.ContinueOnError)
+	var name, prefix, parent string
+
+	switch operation {
+	case "add":
+		fs.StringVar(&name, "name", "", i18n.T("flag.group_name"))
+		fs.StringVar(&prefix, "prefix", "", i18n.T("flag.prefix"))
+		fs.StringVar(&parent, "parent", "", i18n.T("flag.parent"))
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if err := cfg.addGroup(name, prefix, parent); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("group.added", prefix))
+	case "rename":
+		fs.StringVar(&name, "name", "", i18n.T("flag.new_name"))
+		fs.StringVar(&prefix, "prefix", "", i18n.T("flag.new_prefix"))
+		key, err := parseWithId(fs, args)
+		if err != nil {
+			return err
+		}
+		if name == "" && prefix == "" {
+			return errors.New(i18n.T("group.rename_nothing"))
+		}
+
+		if err := cfg.renameGroup(key, name, prefix); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("group.renamed", key))
+	case "rm":
+		var force, ungroup bool
+		var moveTo string
+		fs.BoolVar(&force, "force", false, i18n.T("flag.force"))
+		fs.BoolVar(&ungroup, "ungroup", false, i18n.T("flag.ungroup_servers"))
+		fs.StringVar(&moveTo, "move-to", "", i18n.T("flag.move_to"))
+		key, err := parseWithId(fs, args)
+		if err != nil {
+			return err
+		}
+
+		action := GroupServersRefuse
+		switch {
+		case moveTo != "":
+			action = GroupServersMove
+		case ungroup:
+			action = GroupServersUngroup
+		case force:
+			action = GroupServersDelete
+		}
+
+		if err := cfg.removeGroup(key, action, moveTo); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("group.removed", key))
+	case "move":
+		if len(args) < 2 {
+			return errors.New(i18n.T("group.move_args"))
+		}
+
+		key := args[0]
+		position, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New(i18n.T("input.position_number"))
+		}
+
+		if err := cfg.moveGroup(key, position); err != nil {
+			return err
+		}
+		if err := cfg.saveConfig(true); err != nil {
+			return err
+		}
+
+		utils.Infoln(i18n.T("group.moved", key))
+	default:
+		return errors.New(i18n.T("manage.unknown_operation", operation))
+	}
+
+	return nil
+}
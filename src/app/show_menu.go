@@ -1,13 +1,14 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
 	"strings"
 )
 
 type Operation struct {
 	Key     string
-	Label   string
+	Label   string // 消息key，显示时按当前语言翻译
 	End     bool
 	Process func(cfg *Config, args []string) error
 }
@@ -19,12 +20,16 @@ var operations = make(map[string]Operation)
 func init() {
 	menuMap = [][]Operation{
 		{
-			{Key: "add", Label: "添加", Process: handleAdd},
-			{Key: "edit", Label: "编辑", Process: handleEdit},
-			{Key: "remove", Label: "删除", Process: handleRemove},
+			{Key: "add", Label: "menu.add", Process: handleAdd},
+			{Key: "edit", Label: "menu.edit", Process: handleEdit},
+			{Key: "remove", Label: "menu.remove", Process: handleRemove},
 		},
 		{
-			{Key: "exit", Label: "退出", End: true},
+			{Key: "group", Label: "menu.group", Process: handleGroup},
+			{Key: "move", Label: "menu.move", Process: handleMove},
+		},
+		{
+			{Key: "exit", Label: "menu.exit", End: true},
 		},
 	}
 }
@@ -62,7 +67,7 @@ func showMenu() {
 }
 
 func operationFormat(operation Operation) string {
-	return "[" + operation.Key + "] " + operation.Label
+	return "[" + operation.Key + "] " + i18n.T(operation.Label)
 }
 
 func stringPadding(str string, paddingLen int) string {
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// 连接复用管理
+// mux status [targets]   查看主连接状态
+// mux stop [targets]     关闭主连接
+// mux master index       （内部使用）运行主连接
+func showMux(configFile string) {
+	args := flag.Args()[1:]
+	if len(args) == 0 {
+		utils.Errorln(i18n.T("input.incomplete"))
+		return
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		if args[0] == "master" {
+			fmt.Println(err)
+		} else {
+			utils.Errorln(err)
+		}
+		return
+	}
+
+	switch args[0] {
+	case "master":
+		if len(args) < 2 {
+			fmt.Println(i18n.T("input.incomplete"))
+			return
+		}
+		if err := runMuxMaster(cfg, args[1]); err != nil {
+			fmt.Println(err)
+		}
+	case "status", "stop":
+		targets := args[1:]
+		if len(targets) == 0 {
+			targets = []string{"all"}
+		}
+
+		indexes, err := cfg.resolveTargets(targets)
+		if err != nil {
+			utils.Errorln(err)
+			return
+		}
+
+		for _, index := range indexes {
+			server := cfg.serverIndex[index].server
+			if err := muxControl(server, args[0]); err != nil {
+				utils.Logln(server.FormatPrint(index, cfg.ShowDetail) + "\t" + err.Error())
+			} else {
+				utils.Infoln(server.FormatPrint(index, cfg.ShowDetail) + "\t" + args[0] + " ok")
+			}
+		}
+	default:
+		utils.Errorln(i18n.T("manage.unknown_operation", args[0]))
+	}
+}
+
+// 查看或关闭主连接
+func muxControl(server *Server, operation string) error {
+	path, err := server.controlPath()
+	if err != nil {
+		return err
+	}
+
+	client, err := dialMux(path, server.User)
+	if err != nil {
+		return errors.New(i18n.T("mux.not_running"))
+	}
+	defer client.Close()
+
+	if operation == "stop" {
+		if _, _, err := client.SendRequest(muxRequestExit, true, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
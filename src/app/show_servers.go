@@ -1,8 +1,8 @@
 package app
 
 import (
+	"autossh/src/i18n"
 	"autossh/src/utils"
-	"strconv"
 )
 
 func showServers(configFile string) {
@@ -12,10 +12,16 @@ func showServers(configFile string) {
 		return
 	}
 
+	menuStatus.redraw = func(cfg *Config) {
+		_ = utils.Clear()
+		show(cfg)
+	}
+
 	// 清屏
 	_ = utils.Clear()
 
 	show(cfg)
+	menuStatus.setWaiting(true)
 
 	for {
 		loop, clear, reload := scanInput(cfg)
@@ -23,8 +29,14 @@ func showServers(configFile string) {
 			break
 		}
 
+		// 重新加载失败时保留当前配置
 		if reload {
-			cfg, err = loadConfig(configFile)
+			if newCfg, err := loadConfig(configFile); err != nil {
+				utils.Errorln(err)
+				clear = false
+			} else {
+				cfg = newCfg
+			}
 		}
 
 		if clear {
@@ -32,50 +44,64 @@ func showServers(configFile string) {
 		}
 
 		show(cfg)
+		menuStatus.setWaiting(true)
 	}
 }
 
 // 显示服务
 func show(cfg *Config) {
 	maxlen := separatorLength(*cfg)
-	utils.Infoln(utils.FormatSeparator(" 欢迎使用 Auto SSH ", "=", maxlen))
-	for i, server := range cfg.Servers {
-		utils.Logln(server.FormatPrint(strconv.Itoa(i+1), cfg.ShowDetail))
+	var statuses map[string]bool
+	if cfg.ShowStatus {
+		statuses = menuStatus.get(cfg)
 	}
 
-	for _, group := range cfg.Groups {
-		if len(group.Servers) == 0 {
+	utils.Infoln(utils.FormatSeparator(i18n.T("menu.welcome"), "=", maxlen))
+	for _, server := range cfg.Servers {
+		utils.Logln(statusMarker(statuses, server.index) + server.FormatPrint(server.index, cfg.ShowDetail))
+	}
+
+	showGroups(cfg, cfg.Groups, statuses, maxlen)
+
+	utils.Infoln(utils.FormatSeparator("", "=", maxlen))
+
+	showMenu()
+
+	utils.Infoln(utils.FormatSeparator("", "=", maxlen))
+	utils.Info(i18n.T("menu.prompt"))
+}
+
+// 显示组内服务器，子组跟随上级组显示，折叠时不显示子组
+func showGroups(cfg *Config, groups []*Group, statuses map[string]bool, maxlen int) {
+	for _, group := range groups {
+		if !group.hasServers() {
 			continue
 		}
 
 		var collapseNotice = ""
 		if group.Collapse {
-			collapseNotice = "[" + group.Prefix + " ↓]"
+			collapseNotice = "[" + group.path + " ↓]"
 		} else {
-			collapseNotice = "[" + group.Prefix + " ↑]"
+			collapseNotice = "[" + group.path + " ↑]"
 		}
 
-		utils.Infoln(utils.FormatSeparator(" "+group.GroupName+" "+collapseNotice+" ", "_", maxlen))
-		if !group.Collapse {
-			for i, server := range group.Servers {
-				utils.Logln(server.FormatPrint(group.Prefix+strconv.Itoa(i+1), cfg.ShowDetail))
-			}
+		utils.Infoln(utils.FormatSeparator(" "+group.fullName()+" "+collapseNotice+" ", "_", maxlen))
+		if group.Collapse {
+			continue
 		}
-	}
-
-	utils.Infoln(utils.FormatSeparator("", "=", maxlen))
-
-	showMenu()
 
-	utils.Infoln(utils.FormatSeparator("", "=", maxlen))
-	utils.Info("请输入序号或操作: ")
+		for _, server := range group.Servers {
+			utils.Logln(statusMarker(statuses, server.index) + server.FormatPrint(server.index, cfg.ShowDetail))
+		}
+		showGroups(cfg, group.Groups, statuses, maxlen)
+	}
 }
 
 // 计算分隔符长度
 func separatorLength(cfg Config) int {
 	maxlength := 60
-	for _, group := range cfg.Groups {
-		length := utils.ZhLen(group.GroupName)
+	for _, group := range cfg.allGroups() {
+		length := utils.ZhLen(group.fullName())
 		if length > maxlength {
 			maxlength = length + 10
 		}
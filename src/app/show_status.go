@@ -0,0 +1,403 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultStatusTimeout = 5               // status 命令默认超时秒数
+	menuStatusTimeout    = 2 * time.Second // 菜单中检测在线状态的超时时间
+	menuStatusTTL        = time.Minute     // 菜单中在线状态的缓存时间
+)
+
+// 服务器状态
+type ServerStatus struct {
+	Index     string  `json:"index"`
+	Name      string  `json:"name"`
+	Addr      string  `json:"addr"`
+	Reachable bool    `json:"reachable"`        // TCP 是否可达
+	Banner    string  `json:"banner,omitempty"` // SSH 版本信息
+	Auth      bool    `json:"auth"`             // 是否认证成功
+	Latency   float64 `json:"latency_ms"`       // 建立TCP连接耗时（毫秒）
+	Uptime    string  `json:"uptime,omitempty"` // 运行时间
+	Load      string  `json:"load,omitempty"`   // 负载
+	Error     string  `json:"error,omitempty"`  // 失败原因
+}
+
+// 检查服务器状态
+// status [-json] [-load] [-timeout 5] [targets]
+func showStatus(configFile string) {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	var asJson, load bool
+	var timeout int
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.BoolVar(&asJson, "json", false, i18n.T("flag.status_json"))
+	fs.BoolVar(&load, "load", false, i18n.T("flag.status_load"))
+	fs.IntVar(&timeout, "timeout", defaultStatusTimeout, i18n.T("flag.status_timeout"))
+	if err := fs.Parse(flag.Args()[1:]); err != nil {
+		return
+	}
+
+	if timeout <= 0 {
+		utils.Errorln(i18n.T("status.invalid_timeout"))
+		return
+	}
+
+	targets := fs.Args()
+	if len(targets) == 0 {
+		targets = []string{"all"}
+	}
+
+	indexes, err := cfg.resolveTargets(targets)
+	if err != nil {
+		utils.Errorln(err)
+		return
+	}
+
+	statuses := make([]ServerStatus, len(indexes))
+	var wg sync.WaitGroup
+	for i, index := range indexes {
+		wg.Add(1)
+		go func(i int, index string) {
+			defer wg.Done()
+			statuses[i] = checkStatus(index, cfg.serverIndex[index].server, time.Duration(timeout)*time.Second, load)
+		}(i, index)
+	}
+	wg.Wait()
+
+	if asJson {
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			utils.Errorln(err)
+			return
+		}
+		utils.Logln(string(b))
+		return
+	}
+
+	printStatuses(statuses, load)
+}
+
+// 以表格形式输出
+func printStatuses(statuses []ServerStatus, load bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := i18n.T("status.header")
+	if load {
+		header += i18n.T("status.header_load")
+	}
+	_, _ = fmt.Fprintln(w, header)
+
+	for _, status := range statuses {
+		state := "\033[32mup  \033[0m"
+		if !status.Reachable {
+			state = "\033[31mdown\033[0m"
+		}
+
+		latency := "-"
+		if status.Reachable {
+			latency = strconv.FormatFloat(status.Latency, 'f', 1, 64) + "ms"
+		}
+
+		auth := "ok"
+		if !status.Auth {
+			auth = "fail"
+		}
+
+		line := strings.Join([]string{status.Index, status.Name, status.Addr, state, latency, auth, orDash(status.Banner)}, "\t")
+		if load {
+			line += "\t" + orDash(status.Uptime) + "\t" + orDash(status.Load)
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+
+	for _, status := range statuses {
+		if status.Error != "" {
+			utils.Errorln("[" + status.Index + "] " + status.Error)
+		}
+	}
+}
+
+func orDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+	return str
+}
+
+// 检查单个服务器的状态
+func checkStatus(index string, server *Server, timeout time.Duration, load bool) ServerStatus {
+	quick := server.withTimeout(timeout)
+	status := ServerStatus{
+		Index: index,
+		Name:  server.Name,
+		Addr:  net.JoinHostPort(server.Ip, strconv.Itoa(server.Port)),
+	}
+
+	banner, latency, err := quick.probe(timeout)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Reachable = true
+	status.Banner = banner
+	status.Latency = float64(latency) / float64(time.Millisecond)
+
+	client, err := quick.GetSshClient()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer client.Close()
+	status.Auth = true
+
+	if !load {
+		return status
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer session.Close()
+
+	output := make(chan []byte, 1)
+	go func() {
+		b, _ := session.Output("uptime")
+		output <- b
+	}()
+
+	select {
+	case b := <-output:
+		status.Uptime, status.Load = parseUptime(string(b))
+	case <-time.After(timeout):
+		status.Error = i18n.T("status.load_timeout")
+	}
+
+	return status
+}
+
+// 复制服务器配置并使用指定的超时时间，状态检查不启动连接复用
+func (server *Server) withTimeout(timeout time.Duration) *Server {
+	s := *server
+	s.Options = make(map[string]interface{}, len(server.Options)+3)
+	for k, v := range server.Options {
+		s.Options[k] = v
+	}
+	s.Options["ConnectTimeout"] = timeout.Seconds()
+	s.Options["HandshakeTimeout"] = timeout.Seconds()
+	s.Options["ControlMaster"] = false
+
+	return &s
+}
+
+// 检测TCP是否可达并读取SSH版本信息，返回版本信息及连接耗时
+func (server *Server) probe(timeout time.Duration) (string, time.Duration, error) {
+	dialer, err := server.dialer()
+	if err != nil {
+		return "", 0, err
+	}
+
+	addr := net.JoinHostPort(server.Ip, strconv.Itoa(server.Port))
+	_, isDirect := dialer.(*net.Dialer)
+
+	startTime := time.Now()
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return "", 0, classifyDialError(addr, err, !isDirect)
+	}
+	latency := time.Now().Sub(startTime)
+	defer conn.Close()
+
+	// 服务器在版本信息前可能输出其他内容
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 10; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", latency, &DialError{Kind: classifyNetError(err), Addr: addr, Err: err}
+		}
+		if strings.HasPrefix(line, "SSH-") {
+			return strings.TrimSpace(line), latency, nil
+		}
+	}
+
+	return "", latency, &DialError{Kind: DialErrorUnknown, Addr: addr, Err: errors.New(i18n.T("status.no_banner"))}
+}
+
+// 解析 uptime 命令输出，如
+// 10:01:02 up 3 days,  4:05,  2 users,  load average: 0.00, 0.01, 0.05
+func parseUptime(output string) (string, string) {
+	output = strings.TrimSpace(output)
+
+	load := ""
+	if i := strings.Index(output, "load average"); i >= 0 {
+		load = output[i:]
+		output = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(output[:i]), ","))
+		if j := strings.Index(load, ":"); j >= 0 {
+			load = strings.TrimSpace(load[j+1:])
+		}
+	}
+
+	if i := strings.Index(output, "up "); i >= 0 {
+		output = output[i+3:]
+	}
+
+	// 去掉登录用户数
+	parts := strings.Split(output, ",")
+	if len(parts) > 1 && strings.Contains(parts[len(parts)-1], "user") {
+		parts = parts[:len(parts)-1]
+	}
+
+	uptime := make([]string, 0, len(parts))
+	for _, part := range parts {
+		uptime = append(uptime, strings.TrimSpace(part))
+	}
+
+	return strings.Join(uptime, ", "), load
+}
+
+// 并发检测服务器是否在线，返回编号对应的状态
+func (cfg *Config) probeAll(timeout time.Duration) map[string]bool {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make(map[string]bool)
+
+	for index, serverIndex := range cfg.serverIndex {
+		if index != serverIndex.index {
+			continue
+		}
+
+		// 通过 ProxyCommand 连接时命令启动即视为连接成功，无法判断在线状态
+		if command, _, err := serverIndex.server.effectiveProxy(); err == nil && command != "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(index string, server *Server) {
+			defer wg.Done()
+			_, _, err := server.withTimeout(timeout).probe(timeout)
+
+			mu.Lock()
+			result[index] = err == nil
+			mu.Unlock()
+		}(index, serverIndex.server)
+	}
+	wg.Wait()
+
+	return result
+}
+
+// 在线状态标记，绿色为在线，红色为离线，灰色为未知
+func statusMarker(statuses map[string]bool, index string) string {
+	if statuses == nil {
+		return ""
+	}
+
+	up, ok := statuses[index]
+	switch {
+	case !ok:
+		return "\033[90m●\033[0m"
+	case up:
+		return "\033[32m●\033[0m"
+	default:
+		return "\033[31m●\033[0m"
+	}
+}
+
+// 菜单中的在线状态，在后台检测并缓存，检测完成时若菜单仍在等待输入则重绘
+type statusCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	probe    func(cfg *Config) map[string]bool
+	redraw   func(cfg *Config)
+	cfg      *Config // 当前显示的配置
+	probing  *Config // 正在检测的配置
+	statuses map[string]bool
+	updated  time.Time
+
+	drawMu  sync.Mutex
+	waiting bool
+}
+
+var menuStatus = &statusCache{
+	ttl: menuStatusTTL,
+	probe: func(cfg *Config) map[string]bool {
+		return cfg.probeAll(menuStatusTimeout)
+	},
+}
+
+// 获取缓存的状态，未检测或已过期时在后台检测，检测完成前未知的服务器返回空
+// 重新加载配置后编号可能指向其他服务器，丢弃旧的结果
+func (cache *statusCache) get(cfg *Config) map[string]bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.cfg != cfg {
+		cache.cfg = cfg
+		cache.statuses = nil
+		cache.updated = time.Time{}
+	}
+
+	if time.Since(cache.updated) > cache.ttl && cache.probing != cfg {
+		cache.probing = cfg
+		go cache.update(cfg)
+	}
+
+	statuses := make(map[string]bool, len(cache.statuses))
+	for index, up := range cache.statuses {
+		statuses[index] = up
+	}
+	return statuses
+}
+
+func (cache *statusCache) update(cfg *Config) {
+	statuses := cache.probe(cfg)
+
+	cache.mu.Lock()
+	if cache.probing == cfg {
+		cache.probing = nil
+	}
+	current := cache.cfg == cfg
+	if current {
+		cache.statuses = statuses
+		cache.updated = time.Now()
+	}
+	cache.mu.Unlock()
+
+	if !current {
+		return
+	}
+
+	cache.drawMu.Lock()
+	defer cache.drawMu.Unlock()
+	if cache.waiting {
+		cache.redraw(cfg)
+	}
+}
+
+// 设置菜单是否在等待输入，不在等待输入时检测完成不重绘，避免覆盖其他输出
+func (cache *statusCache) setWaiting(waiting bool) {
+	cache.drawMu.Lock()
+	cache.waiting = waiting
+	cache.drawMu.Unlock()
+}
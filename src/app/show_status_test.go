@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUptime(t *testing.T) {
+	cases := []struct {
+		output string
+		uptime string
+		load   string
+	}{
+		{" 10:01:02 up 3 days,  4:05,  2 users,  load average: 0.00, 0.01, 0.05\n", "3 days, 4:05", "0.00, 0.01, 0.05"},
+		{"10:01  up 12 mins, 1 user, load averages: 1.52 1.61 1.70", "12 mins", "1.52 1.61 1.70"},
+		{"", "", ""},
+	}
+
+	for _, c := range cases {
+		uptime, load := parseUptime(c.output)
+		if uptime != c.uptime || load != c.load {
+			t.Errorf("parseUptime(%q) = (%q, %q), want (%q, %q)", c.output, uptime, load, c.uptime, c.load)
+		}
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	sshListener := startTestSshServer(t, "secret")
+	defer sshListener.Close()
+	port := sshListener.Addr().(*net.TCPAddr).Port
+
+	status := checkStatus("1", &Server{Ip: "127.0.0.1", Port: port, User: "test", Password: "secret"}, time.Second, false)
+	if !status.Reachable || !status.Auth || status.Error != "" {
+		t.Errorf("status = %+v", status)
+	}
+	if status.Banner == "" {
+		t.Error("banner is empty")
+	}
+
+	status = checkStatus("2", &Server{Ip: "127.0.0.1", Port: port, User: "test", Password: "wrong"}, time.Second, false)
+	if !status.Reachable || status.Auth {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestStatusCache(t *testing.T) {
+	probed := make(chan *Config, 4)
+	redrawn := make(chan *Config, 4)
+	cache := &statusCache{
+		ttl: time.Hour,
+		probe: func(cfg *Config) map[string]bool {
+			probed <- cfg
+			return map[string]bool{"1": true}
+		},
+		redraw: func(cfg *Config) { redrawn <- cfg },
+	}
+
+	cfg := &Config{}
+	cache.setWaiting(true)
+	if statuses := cache.get(cfg); statuses == nil || len(statuses) != 0 {
+		t.Errorf("statuses before probe = %v", statuses)
+	}
+
+	<-probed
+	select {
+	case c := <-redrawn:
+		if c != cfg {
+			t.Error("redraw with wrong config")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("menu not redrawn")
+	}
+
+	// 未过期时使用缓存，不再检测
+	if statuses := cache.get(cfg); !statuses["1"] {
+		t.Errorf("cached statuses = %v", statuses)
+	}
+	select {
+	case <-probed:
+		t.Error("probed again within ttl")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 重新加载配置后丢弃旧结果；不在等待输入时不重绘
+	cache.setWaiting(false)
+	reloaded := &Config{}
+	if statuses := cache.get(reloaded); len(statuses) != 0 {
+		t.Errorf("statuses after reload = %v", statuses)
+	}
+	<-probed
+	select {
+	case <-redrawn:
+		t.Error("redrawn while not waiting for input")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStatusMarker(t *testing.T) {
+	statuses := map[string]bool{"1": true, "2": false}
+	if statusMarker(nil, "1") != "" {
+		t.Error("marker without status should be empty")
+	}
+	if !strings.Contains(statusMarker(statuses, "1"), "32m") || !strings.Contains(statusMarker(statuses, "2"), "31m") || !strings.Contains(statusMarker(statuses, "3"), "90m") {
+		t.Error("unexpected markers")
+	}
+
+	// 通过 ProxyCommand 连接的服务器不检测，显示为未知
+	var cfg Config
+	raw := `{"servers": [{"name": "a", "ip": "127.0.0.1", "port": 1, "proxy_command": "nc %h %p"}]}`
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.probeAll(time.Second)["1"]; ok {
+		t.Error("proxy command server should not be probed")
+	}
+}
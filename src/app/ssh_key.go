@@ -0,0 +1,149 @@
+package app
+
+import (
+	"autossh/src/i18n"
+	"autossh/src/utils"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// 默认生成的密钥路径
+const defaultKeyFile = "~/.ssh/autossh_ed25519"
+
+// 生成 ed25519 密钥，私钥使用 OpenSSH 格式（不加密），公钥写入 file.pub
+// 文件已存在时返回错误，不覆盖
+func generateKey(file string, comment string) (ssh.PublicKey, error) {
+	file, err := utils.ParsePath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range []string{file, file + ".pub"} {
+		if exists, _ := utils.FileIsExists(f); exists {
+			return nil, errors.New(i18n.T("key.exists", f))
+		}
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := marshalEd25519PrivateKey(priv, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, err
+	}
+
+	authorizedKey := ssh.MarshalAuthorizedKey(publicKey)
+	if comment != "" {
+		authorizedKey = append(authorizedKey[:len(authorizedKey)-1], []byte(" "+comment+"\n")...)
+	}
+	if err := ioutil.WriteFile(file+".pub", authorizedKey, 0644); err != nil {
+		return nil, err
+	}
+
+	return publicKey, nil
+}
+
+// 按 OpenSSH 私钥格式（PROTOCOL.key）编码 ed25519 私钥，不加密
+// 当前依赖的 x/crypto 版本未提供 MarshalPrivateKey
+func marshalEd25519PrivateKey(key ed25519.PrivateKey, comment string) (*pem.Block, error) {
+	pub := key.Public().(ed25519.PublicKey)
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	private := struct {
+		Check1  uint32
+		Check2  uint32
+		KeyType string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+		Pad     []byte `ssh:"rest"`
+	}{
+		Check1:  checkInt,
+		Check2:  checkInt,
+		KeyType: ssh.KeyAlgoED25519,
+		Pub:     pub,
+		Priv:    key,
+		Comment: comment,
+	}
+
+	// 未加密时按8字节对齐，填充内容为 1, 2, 3...
+	length := len(ssh.Marshal(private))
+	for i := 0; (length+i)%8 != 0; i++ {
+		private.Pad = append(private.Pad, byte(i+1))
+	}
+
+	body := struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       publicKey.Marshal(),
+		PrivKeyBlock: ssh.Marshal(private),
+	}
+
+	return &pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: append([]byte("openssh-key-v1\x00"), ssh.Marshal(body)...),
+	}, nil
+}
+
+// 读取公钥，优先读取 file.pub，不存在时从私钥中获取
+func loadPublicKey(file string) (ssh.PublicKey, error) {
+	file, err := utils.ParsePath(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err := ioutil.ReadFile(file + ".pub"); err == nil {
+		key, _, _, _, err := ssh.ParseAuthorizedKey(b)
+		return key, err
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.PublicKey(), nil
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autossh-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "id_ed25519")
+	pub, err := generateKey(file, "test@autossh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signer.PublicKey().Marshal()) != string(pub.Marshal()) {
+		t.Error("private key does not match public key")
+	}
+
+	if loaded, err := loadPublicKey(file); err != nil || string(loaded.Marshal()) != string(pub.Marshal()) {
+		t.Errorf("loadPublicKey = %v", err)
+	}
+	if b, _ := ioutil.ReadFile(file + ".pub"); !strings.HasSuffix(string(b), " test@autossh\n") {
+		t.Errorf("public key = %q", b)
+	}
+
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("private key mode = %v", info.Mode())
+	}
+
+	if _, err := generateKey(file, ""); err == nil {
+		t.Error("expected error for existing key")
+	}
+}
+
+func TestAuthorizedKeysCmd(t *testing.T) {
+	home, err := ioutil.TempDir("", "autossh-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	run := func(cmd string) {
+		c := exec.Command("sh", "-c", cmd)
+		c.Env = append(os.Environ(), "HOME="+home)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v %s", cmd, err, out)
+		}
+	}
+
+	oldKey := newTestSigner(t).PublicKey()
+	newKey := newTestSigner(t).PublicKey()
+	file := filepath.Join(home, ".ssh", "authorized_keys")
+
+	// 重复安装不重复添加
+	run(authorizedKeysAppendCmd(oldKey))
+	run(authorizedKeysAppendCmd(newKey))
+	run(authorizedKeysAppendCmd(newKey))
+
+	b, _ := ioutil.ReadFile(file)
+	if strings.Count(string(b), authorizedKeyLine(newKey)) != 1 || !strings.Contains(string(b), authorizedKeyLine(oldKey)) {
+		t.Errorf("authorized_keys:\n%s", b)
+	}
+
+	run(authorizedKeysRemoveCmd(oldKey))
+	b, _ = ioutil.ReadFile(file)
+	if strings.Contains(string(b), authorizedKeyLine(oldKey)) || !strings.Contains(string(b), authorizedKeyLine(newKey)) {
+		t.Errorf("authorized_keys after remove:\n%s", b)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("authorized_keys mode = %v", info.Mode())
+	}
+}
+
+func TestRotateDefaultKey(t *testing.T) {
+	server := Server{Name: "web", Ip: "127.0.0.1", Port: 1, Method: "key"}
+	if file := server.keyFile(); file != defaultServerKey {
+		t.Errorf("keyFile = %q", file)
+	}
+
+	// 未指定 -old 且服务器使用默认密钥时，旧密钥为 ~/.ssh/id_rsa
+	cfg := &Config{Servers: []*Server{&server}}
+	if err := cfg.createServerIndex(); err != nil {
+		t.Fatal(err)
+	}
+	installer := &keyInstaller{cfg: cfg, rotate: true, timeout: time.Second}
+	if err := installer.install("1"); err == nil {
+		t.Error("expected error for unreachable server")
+	}
+}
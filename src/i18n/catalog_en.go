@@ -0,0 +1,296 @@
+package i18n
+
+var en = map[string]string{
+	"help.usage": `An ssh client that logs in to remote servers with one keystroke, making up for the Mac/Linux terminal ssh being unable to save passwords.
+Usage:
+  autossh [options] [commands]
+
+Options:
+  -c, -config string    Config file (default: ./config.json).
+  -v, -version          Show version.
+  -vvv                  Print debug output for each connection stage.
+  -h, -help             Show help.
+
+Commands:
+  cp [-r] [-limit 5M] [-buffer 64K] [-include pattern] [-exclude pattern] source target
+                           Copy files. source/target are local paths or server:path (server may be an index, alias or IP; use [::1]:/path for IPv6).
+                           Sources may contain globs; -include/-exclude may be repeated to copy or skip matching files in directories;
+                           -limit caps the bytes per second, -buffer sets the read/write buffer size.
+  cp -tar [-compress gzip|zstd] source target
+                           Stream a tar through an exec session running the remote tar; suited to trees of many small files. target is a directory.
+  cluster [-layout prefix|tmux] targets
+                           Cluster mode: log in to several servers and broadcast input. targets may be indexes, aliases, group prefixes or all.
+  mux status|stop [targets]
+                           Show or stop shared master connections (requires the ControlMaster option).
+  status [-json] [-load] [-timeout 5] [targets]
+                           Check reachability, SSH version, auth and latency concurrently; -load also reports uptime and load.
+  list [-format table|json|csv] [targets]
+                           Print the server inventory (without passwords) for scripts.
+  export ansible [-format ini|yaml] [-list] [-host name] [targets]
+                           Export an Ansible inventory (without passwords); -list/-host work as a dynamic inventory.
+  server add -name name -ip ip [-port 22] [-user root] [-password-source source] [-method password|key] [-key path] [-cert path] [-alias alias] [-group prefix]
+  server edit id [-port 2222 ...]
+  server rm id
+  server move id [-group prefix | -ungroup] [-position n]
+                           Add, edit, remove or move servers; the config file is backed up before changes.
+  group add -name name -prefix prefix [-parent prefix]
+  group rename prefix [-name name] [-prefix prefix]
+  group rm prefix [-force | -ungroup | -move-to prefix]
+  group move prefix position
+                           Add, edit, remove or reorder groups; removing a group lets you choose what happens to its servers.
+  key gen [-key ~/.ssh/autossh_ed25519] [-comment comment]
+  key install [-key path] targets
+  key rotate [-key path] [-old path] targets
+                           Generate an ed25519 key; install a public key and switch servers to key auth; replace an old key with a new one.
+  ${ServerNum}             Log in to a server by index.
+  ${ServerAlias}           Log in to a server by alias.
+  upgrade                  Check for and install the latest version.
+
+The language follows the LC_ALL and LANG environment variables, or set "language": "en" or "zh" in the config file.
+`,
+
+	// 菜单
+	"menu.add":     "Add",
+	"menu.edit":    "Edit",
+	"menu.remove":  "Remove",
+	"menu.group":   "Groups",
+	"menu.move":    "Move",
+	"menu.exit":    "Exit",
+	"menu.welcome": " Welcome to Auto SSH ",
+	"menu.prompt":  "Enter an index or operation: ",
+
+	// 输入
+	"input.invalid":         "Invalid input, please try again",
+	"input.index":           "Enter the server index: ",
+	"input.index_not_found": "Index does not exist",
+	"input.position_number": "Position must be a number",
+	"input.position_range":  "Position must be between 1 and %d",
+
+	"scan.selected":      "Selected %s",
+	"scan.backup_failed": "Backup failed",
+
+	// 服务器管理
+	"add.default_group":      "[other]default group",
+	"add.prompt_group":       "Enter the group to add to: ",
+	"server.added":           "Server added: %s",
+	"server.not_found":       "Server %s does not exist",
+	"validate.name_empty":    "Name must not be empty",
+	"validate.ip_empty":      "IP must not be empty",
+	"validate.port_range":    "Port must be between 1 and 65535",
+	"validate.key_missing":   "Key file %s does not exist",
+	"validate.method":        "Method must be password or key",
+	"alias.invalid":          "Alias %s is not allowed",
+	"alias.used":             "Alias %s is already in use",
+	"alias.index_conflict":   "Alias %s conflicts with a server index",
+	"alias.group_conflict":   "Alias %s conflicts with group %s",
+	"alias.command_conflict": "Alias %s conflicts with a subcommand",
+	"move.prompt_server":     "Enter the index of the server to move: ",
+	"move.default_group":     "[ungroup]default group",
+	"move.prompt_group":      "Target group",
+	"move.prompt_position":   "Enter the position in the group (empty to append): ",
+	"move.new_index":         "New server index: %s",
+	"prefix.invalid":         "Prefix %s is not allowed: it must not be empty, contain spaces or '.', or end with a digit",
+	"prefix.used":            "Prefix %s is already in use",
+	"prefix.alias_conflict":  "Prefix %s conflicts with an alias",
+	"group.operations":       "[add]new [rename]rename [remove]remove [move]reorder",
+	"group.prompt_operation": "Enter an operation: ",
+	"group.prompt_parent":    "Enter the parent group prefix (empty for a top-level group): ",
+	"group.prompt_name":      "Enter the group name: ",
+	"group.prompt_prefix":    "Enter the prefix: ",
+	"group.prompt_rename":    "Enter the prefix of the group to rename: ",
+	"group.prompt_remove":    "Enter the prefix of the group to remove: ",
+	"group.prompt_move":      "Enter the prefix of the group to move: ",
+	"group.prompt_choose":    "Choose: ",
+	"group.prompt_position":  "Enter the target position (1-%d): ",
+	"group.field_name":       "Group name",
+	"group.field_prefix":     "Prefix",
+	"group.remove_servers":   "The group has %d servers: [delete]delete them with subgroups [ungroup]move to the default group [other prefix]move to that group",
+	"group.not_found":        "Group %s does not exist",
+	"group.name_empty":       "Group name must not be empty",
+	"group.not_empty":        "Group %s still has servers or subgroups, remove or move them first",
+	"group.move_to_self":     "Cannot move servers to the group being removed",
+	"group.child_conflict":   "The prefix of subgroup %s conflicts with a group on the parent level",
+
+	// 更新
+	"upgrade.checking":        "Checking for the latest version",
+	"upgrade.current":         "Current version: %s",
+	"upgrade.up_to_date":      "You are on the latest version. Thanks for your support.",
+	"upgrade.found":           "New version found: %s",
+	"upgrade.unsupported":     "Automatic upgrade is not supported on %s, please build from source.",
+	"upgrade.download_failed": "Download failed: %v",
+	"upgrade.unzip_failed":    "Unzip failed: %v",
+	"upgrade.install_failed":  "Install failed",
+
+	// 连接
+	"server.fd_failed":           "Failed to get the terminal file descriptor: %v",
+	"server.shell_failed":        "Failed to start the shell: %v",
+	"server.pty_failed":          "Failed to request a terminal: %v",
+	"dial.dns":                   "Cannot resolve host %s: %v",
+	"dial.refused":               "Connection refused, check that the address and port %s are correct",
+	"dial.timeout":               "Connection to %s timed out, check the network or raise the ConnectTimeout/HandshakeTimeout options",
+	"dial.auth":                  "Authentication failed (tried: %s), check the password/key",
+	"dial.hostkey":               "Host key verification failed: %v",
+	"dial.proxy":                 "Proxy connection failed (%s): %v",
+	"dialer.direct":              "(direct)",
+	"dialer.command":             "(ProxyCommand: %s)",
+	"dialer.proxy":               "(proxy)",
+	"cause.dns":                  "cannot resolve address",
+	"cause.refused":              "connection refused",
+	"cause.timeout":              "connection timed out",
+	"cause.closed":               "connection closed",
+	"cause.unknown":              "unknown error",
+	"secret.invalid_source":      "Invalid password source %s, expected cmd:command, env:NAME, file:path or keyring:service/account",
+	"secret.env_not_set":         "Environment variable %s is not set",
+	"secret.keyring_unsupported": "Reading the keyring is not supported on %s",
+	"secret.command_failed":      "%s failed: %s",
+	"cert.invalid":               "Invalid certificate %s: %v",
+	"cert.not_user_cert":         "%s is not a user certificate",
+	"cert.key_mismatch":          "Certificate %s does not match the key",
+	"cert.not_yet_valid":         "%s is not valid until %s",
+	"cert.expired":               "%s expired at %s, please have the certificate reissued",
+	"cert.invalid_ca":            "Invalid host_ca entry %s, expected a public key or a public key file",
+	"cert.host_name":             "The certificate of host %s",
+	"debug.cert":                 "using certificate %s, key id %s, principals %s",
+	"key.exists":                 "File %s already exists",
+	"key.generated":              "Generated key %s (%s)",
+	"key.no_targets":             "Please specify target servers",
+	"key.no_old_key":             "The server does not use key auth, specify the old key with -old",
+	"key.installed":              "Public key installed, switched to key auth",
+	"key.verify_failed":          "Login with the new key failed, config unchanged: %v",
+	"key.failed":                 "%d/%d servers failed",
+	"escape.disconnected":        "disconnected from %s",
+	"escape.help":                "Supported escape sequences:\n %[1]s.  terminate connection\n %[1]sB  send a BREAK\n %[1]sC  open a command line (-L [bind:]port:host:hostport, -KL [bind:]port, cp [-r] source target)\n %[1]ss  show connection status (also %[1]s#)\n %[1]s?  this message\n %[1]s%[1]s  send the escape character\n(Escapes are only recognized immediately after a newline.)",
+	"escape.stats_server":        "server: %s (%v)",
+	"escape.stats_duration":      "connected for %v",
+	"escape.stats_bytes":         "sent %d bytes, received %d bytes",
+	"escape.stats_forward":       "forwarding: %s -> %s",
+	"escape.invalid_command":     "unsupported command: %s, available commands are -L, -KL and cp",
+	"escape.invalid_forward":     "invalid forward %s, expected [bind:]port:host:hostport",
+	"escape.forward_exists":      "%s is already forwarded",
+	"escape.forward_added":       "forwarding %s -> %s",
+	"escape.forward_not_found":   "no forward listening on %s",
+	"escape.forward_canceled":    "canceled forward %s",
+	"zmodem.not_installed":       "rz/sz not found locally, please install lrzsz",
+	"zmodem.prompt_upload":       "Files to upload, separated by spaces (empty to cancel): ",
+	"zmodem.file_not_found":      "file %s does not exist",
+	"zmodem.progress_received":   "received %s, %s/s",
+	"zmodem.progress_sent":       "sent %s, %s/s",
+	"zmodem.received":            "transfer complete, files saved to %s",
+	"zmodem.sent":                "upload complete",
+	"zmodem.canceled":            "transfer canceled",
+	"zmodem.failed":              "transfer failed: %s",
+	"cp.invalid_limit":           "invalid limit %s, expected a size such as 512K or 5M",
+	"cp.invalid_buffer":          "invalid buffer size %s, expected a size such as 32K or 1M",
+	"cp.summary":                 "%d files transferred, %s, average %s/s, elapsed %s, %d failed",
+	"cp.invalid_pattern":         "invalid pattern %s",
+	"server.ip_ambiguous":        "several servers use IP %s, please use an index or alias",
+	"cp.invalid_compress":        "invalid compression %s, expected gzip or zstd",
+	"cp.tar_remote_to_remote":    "tar mode only copies between local and a server",
+	"cp.tar_unsafe_path":         "archive entry %s points outside the target directory, extraction stopped",
+	"cp.zstd_not_found":          "cannot run local zstd: %v",
+	"cp.tar_progress":            "transferred %s (%s on the wire), %s/s, elapsed %s",
+	"debug.compression":          "x/crypto/ssh does not support SSH-level compression, the Compression option is ignored; use cp -tar -compress gzip instead",
+	"cp.tar_unsupported_type":    "unsupported entry type %q, skipped",
+	"cp.tar_is_dir":              "%s already exists and is a directory",
+	"cp.tar_bad_link":            "hard link target %s is not an extracted regular file",
+	"input.incomplete":           "Missing arguments",
+	"input.index_required":       "Please enter an index",
+	"flag.name":                  "name",
+	"flag.ip":                    "IP or host name",
+	"flag.port":                  "port",
+	"flag.user":                  "user name",
+	"flag.method":                "auth method: password/key",
+	"flag.key":                   "key path",
+	"flag.alias":                 "alias",
+	"flag.add_group":             "group prefix or name, empty for the default group",
+	"flag.move_group":            "target group prefix or name, empty to keep the current group",
+	"flag.ungroup":               "move to the default group",
+	"flag.position":              "position in the group starting at 1, 0 to append",
+	"flag.group_name":            "group name",
+	"flag.prefix":                "prefix",
+	"flag.parent":                "parent group prefix, empty for a top-level group",
+	"flag.new_name":              "new group name",
+	"flag.new_prefix":            "new prefix",
+	"flag.force":                 "also delete the servers in the group",
+	"flag.ungroup_servers":       "move the servers in the group to the default group",
+	"flag.move_to":               "move the servers in the group to this group",
+	"server.edit_nothing":        "Please specify what to change",
+	"server.edited":              "Server updated: %s",
+	"server.removed":             "Server removed: %s",
+	"group.added":                "Group added: %s",
+	"group.rename_nothing":       "Please specify a new group name or prefix",
+	"group.renamed":              "Group updated: %s",
+	"group.removed":              "Group removed: %s",
+	"group.move_args":            "Please enter the group prefix and the target position",
+	"group.moved":                "Group moved: %s",
+	"mux.start_failed":           "Failed to start the master connection: %s",
+	"mux.not_running":            "Master connection is not running",
+	"flag.config":                "config file path",
+	"flag.version":               "show version",
+	"flag.verbose":               "print connection debug output",
+	"flag.help":                  "show help",
+	"version.author":             "Written by Lenbo, project home: https://github.com/islenbo/autossh.",
+	"config.index_duplicate":     "Index %s is duplicated, check the group prefixes and aliases",
+	"config.alias_duplicate":     "Alias %s duplicates the index or alias of another server",
+	"config.alias_command":       "Alias %s has the same name as a subcommand and cannot be used on the command line, please rename it",
+	"config.id_negative":         "The id of server %s must not be negative",
+	"config.id_duplicate":        "Index %s is duplicated",
+	"config.backup":              "Config file backed up: %s",
+	"flag.cp_dir":                "copy directories recursively",
+	"cp.same_side":               "Source and target cannot both be local",
+	"cp.is_dir":                  "is a directory",
+	"flag.list_format":           "output format: table/json/csv",
+	"list.header":                "INDEX\tALIAS\tNAME\tGROUP\tHOST\tPORT\tUSER\tMETHOD\tPROXY",
+	"list.invalid_format":        "Unsupported output format: %s",
+	"export.type_required":       "Please enter the export type",
+	"export.invalid_type":        "Unsupported export type: %s",
+	"export.proxy_auth":          "The proxy of server %s requires authentication, which nc does not support, please set proxy_command instead",
+	"flag.export_format":         "output format: ini/yaml",
+	"flag.export_list":           "dynamic inventory mode, print all hosts",
+	"flag.export_host":           "dynamic inventory mode, print the variables of this host",
+	"flag.status_json":           "print JSON",
+	"flag.status_load":           "log in and fetch uptime and load",
+	"flag.status_timeout":        "timeout in seconds",
+	"status.invalid_timeout":     "Timeout must be greater than 0",
+	"status.header":              "INDEX\tNAME\tADDRESS\tSTATE\tLATENCY\tAUTH\tVERSION",
+	"status.header_load":         "\tUPTIME\tLOAD",
+	"status.load_timeout":        "timed out fetching the load",
+	"status.no_banner":           "no SSH version received",
+	"script.timeout":             "timed out waiting for output",
+	"flag.cluster_layout":        "layout: prefix/tmux",
+	"cluster.no_targets":         "Please enter the target servers",
+	"cluster.invalid_layout":     "Unsupported layout: %s",
+	"cluster.tmux_required":      "The tmux layout must be used inside a tmux session",
+	"cluster.tmux_window_failed": "Failed to create the tmux window: %v",
+	"cluster.tmux_pane_failed":   "Failed to create the tmux pane: %v",
+	"cluster.no_connections":     "No servers connected",
+	"cluster.connected":          "Connected to %d servers, press Ctrl-] for control mode",
+	"cluster.disconnected":       "%s disconnected",
+	"cluster.prompt":             "cluster [index]toggle [a]enable all [l]list [q]quit > ",
+	"cluster.invalid_input":      "Invalid input: %s",
+	"flag.password_source":       "password source, e.g. cmd:pass show web, env:WEB_PASSWORD, file:path, keyring:service/account; passwords are not accepted on the command line",
+	"flag.cert":                  "certificate path, defaults to <key path>-cert.pub",
+	"flag.key_file":              "key path, generated when missing",
+	"flag.key_comment":           "public key comment",
+	"flag.key_timeout":           "connect timeout in seconds",
+	"flag.key_old":               "old key to replace, defaults to the key the server uses now",
+	"flag.cp_limit":              "rate limit in bytes per second, e.g. 512K, 5M",
+	"flag.cp_buffer":             "read/write buffer size, e.g. 32K, 1M",
+	"flag.cp_include":            "only copy matching files when copying directories, may be repeated",
+	"flag.cp_exclude":            "skip matching files and directories when copying directories, may be repeated",
+	"flag.cp_tar":                "stream directories as a tar through an exec session running the remote tar",
+	"flag.cp_compress":           "compression for tar mode: gzip|zstd",
+	"cert.host_untrusted":        "%s did not present a certificate signed by host_ca and its key is not trusted by %s: %v",
+	"debug.env_proxy_none":       "proxy type ENV but ALL_PROXY/HTTPS_PROXY is not set, connecting directly",
+	"debug.env_no_proxy":         "NO_PROXY matches %s, connecting directly",
+	"debug.env_proxy":            "using %s proxy %s from the environment",
+	"manage.unknown_operation":   "Unknown operation: %s",
+	"debug.try_password":         "trying password authentication",
+	"debug.try_publickey":        "trying publickey authentication:",
+	"debug.host_key":             "host key:",
+	"debug.banner":               "server banner:",
+	"debug.connecting":           "connecting to %s, connect timeout %v %s",
+	"debug.connected":            "connected to %v in %v",
+	"debug.handshake":            "starting SSH handshake as %s, handshake timeout %v",
+	"debug.authenticated":        "authenticated, server version %s, total %v",
+}
@@ -0,0 +1,296 @@
+package i18n
+
+var zh = map[string]string{
+	"help.usage": `一个ssh远程客户端，可一键登录远程服务器，主要用来弥补Mac/Linux Terminal ssh无法保存密码的不足。
+Usage:
+  autossh [options] [commands]
+
+Options:
+  -c, -config string    指定配置文件(default: ./config.json)。
+  -v, -version          显示版本信息。
+  -vvv                  输出连接各阶段的调试信息。
+  -h, -help             显示帮助信息。
+
+Commands:
+  cp [-r] [-limit 5M] [-buffer 64K] [-include pattern] [-exclude pattern] source target
+                           复制传输，source/target 为本地路径或 服务器:路径（服务器可为编号、别名或IP，IPv6 使用 [::1]:/path），
+                           源路径支持通配符；-include/-exclude 可重复指定，复制目录时只传输或跳过匹配的文件；
+                           -limit 限制每秒传输的字节数，-buffer 指定读写缓冲区大小。
+  cp -tar [-compress gzip|zstd] source target
+                           打包传输，通过 exec 会话执行远程的 tar，适合包含大量小文件的目录，target 为目录。
+  cluster [-layout prefix|tmux] targets
+                           集群模式，同时登录多台服务器并广播输入，targets 可为编号、别名、组前缀或 all。
+  mux status|stop [targets]
+                           查看或关闭复用的主连接（需开启 ControlMaster 选项）。
+  status [-json] [-load] [-timeout 5] [targets]
+                           并发检测服务器是否可达、SSH版本、认证及延迟，-load 同时获取运行时间及负载。
+  list [-format table|json|csv] [targets]
+                           输出服务器清单（不含密码），便于脚本使用。
+  export ansible [-format ini|yaml] [-list] [-host name] [targets]
+                           导出 Ansible 清单（不含密码），-list/-host 用于动态清单。
+  server add -name name -ip ip [-port 22] [-user root] [-password-source source] [-method password|key] [-key path] [-cert path] [-alias alias] [-group prefix]
+  server edit id [-port 2222 ...]
+  server rm id
+  server move id [-group prefix | -ungroup] [-position n]
+                           添加、修改、删除、移动服务器，修改前自动备份配置文件。
+  group add -name name -prefix prefix [-parent prefix]
+  group rename prefix [-name name] [-prefix prefix]
+  group rm prefix [-force | -ungroup | -move-to prefix]
+  group move prefix position
+                           添加、修改、删除组及调整组的顺序，删除时可选择组内服务器的去向。
+  key gen [-key ~/.ssh/autossh_ed25519] [-comment comment]
+  key install [-key path] targets
+  key rotate [-key path] [-old path] targets
+                           生成 ed25519 密钥；安装公钥到服务器并切换为密钥认证；将旧密钥替换为新密钥。
+  ${ServerNum}             使用编号登录指定服务器。
+  ${ServerAlias}           使用别名登录指定服务器。
+  upgrade                  检测并更新到最新版本。
+
+语言默认按 LC_ALL、LANG 环境变量选择，也可在配置文件中设置 "language": "en" 或 "zh"。
+`,
+
+	// 菜单
+	"menu.add":     "添加",
+	"menu.edit":    "编辑",
+	"menu.remove":  "删除",
+	"menu.group":   "组管理",
+	"menu.move":    "移动",
+	"menu.exit":    "退出",
+	"menu.welcome": " 欢迎使用 Auto SSH ",
+	"menu.prompt":  "请输入序号或操作: ",
+
+	// 输入
+	"input.invalid":         "输入有误，请重新输入",
+	"input.index":           "请输入相应序号：",
+	"input.index_not_found": "序号不存在",
+	"input.position_number": "位置必须为数字",
+	"input.position_range":  "位置范围为1-%d",
+
+	"scan.selected":      "你选择了 %s",
+	"scan.backup_failed": "备份失败",
+
+	// 服务器管理
+	"add.default_group":      "[其他值]默认组",
+	"add.prompt_group":       "请输入要插入的组：",
+	"server.added":           "已添加服务器：%s",
+	"server.not_found":       "服务器%s不存在",
+	"validate.name_empty":    "名称不能为空",
+	"validate.ip_empty":      "IP不能为空",
+	"validate.port_range":    "端口范围为1-65535",
+	"validate.key_missing":   "密钥文件 %s 不存在",
+	"validate.method":        "认证方式只能为 password 或 key",
+	"alias.invalid":          "别名%s不可用",
+	"alias.used":             "别名%s已被使用",
+	"alias.index_conflict":   "别名%s与编号冲突",
+	"alias.group_conflict":   "别名%s与组%s冲突",
+	"alias.command_conflict": "别名%s与子命令冲突",
+	"move.prompt_server":     "请输入要移动的服务器序号：",
+	"move.default_group":     "[ungroup]默认组",
+	"move.prompt_group":      "请输入目标组",
+	"move.prompt_position":   "请输入在组内的位置（为空时移动到末尾）：",
+	"move.new_index":         "服务器新编号：%s",
+	"prefix.invalid":         "前缀%s不可用，前缀不能为空、包含空格及 . 或以数字结尾",
+	"prefix.used":            "前缀%s已被使用",
+	"prefix.alias_conflict":  "前缀%s与别名冲突",
+	"group.operations":       "[add]新建 [rename]重命名 [remove]删除 [move]排序",
+	"group.prompt_operation": "请输入操作：",
+	"group.prompt_parent":    "请输入上级组前缀（为空时创建顶级组）：",
+	"group.prompt_name":      "请输入组名：",
+	"group.prompt_prefix":    "请输入前缀：",
+	"group.prompt_rename":    "请输入要修改的组前缀：",
+	"group.prompt_remove":    "请输入要删除的组前缀：",
+	"group.prompt_move":      "请输入要移动的组前缀：",
+	"group.prompt_choose":    "请选择：",
+	"group.prompt_position":  "请输入目标位置(1-%d)：",
+	"group.field_name":       "组名",
+	"group.field_prefix":     "前缀",
+	"group.remove_servers":   "组内有%d台服务器：[delete]一并删除（含子组） [ungroup]移动到默认组 [其他组前缀]移动到该组",
+	"group.not_found":        "组%s不存在",
+	"group.name_empty":       "组名不能为空",
+	"group.not_empty":        "组%s内还有服务器或子组，请先删除或移动",
+	"group.move_to_self":     "不能移动到被删除的组",
+	"group.child_conflict":   "子组%s的前缀与上一级的组冲突",
+
+	// 更新
+	"upgrade.checking":        "正在检测最新版本",
+	"upgrade.current":         "当前版本：%s",
+	"upgrade.up_to_date":      "感谢您的支持，当前已是最新版本。",
+	"upgrade.found":           "检测到新版本：%s",
+	"upgrade.unsupported":     "暂不支持%s系统自动更新，请下载源码包手动编译。",
+	"upgrade.download_failed": "下载失败：%v",
+	"upgrade.unzip_failed":    "解压缩失败：%v",
+	"upgrade.install_failed":  "安装失败",
+
+	// 连接
+	"server.fd_failed":           "创建文件描述符出错:%v",
+	"server.shell_failed":        "执行Shell出错:%v",
+	"server.pty_failed":          "创建终端出错:%v",
+	"dial.dns":                   "无法解析主机地址 %s：%v",
+	"dial.refused":               "连接被拒绝，请检查地址及端口 %s 是否正确",
+	"dial.timeout":               "连接 %s 超时，请检查网络或调大 ConnectTimeout/HandshakeTimeout 选项",
+	"dial.auth":                  "认证失败（已尝试：%s），请检查密码/密钥是否有误",
+	"dial.hostkey":               "主机密钥校验失败：%v",
+	"dial.proxy":                 "代理连接失败（%s）：%v",
+	"dialer.direct":              "（直连）",
+	"dialer.command":             "（ProxyCommand：%s）",
+	"dialer.proxy":               "（代理）",
+	"cause.dns":                  "无法解析地址",
+	"cause.refused":              "连接被拒绝",
+	"cause.timeout":              "连接超时",
+	"cause.closed":               "连接被关闭",
+	"cause.unknown":              "未知错误",
+	"secret.invalid_source":      "密码来源%s有误，格式为 cmd:命令、env:变量名、file:路径 或 keyring:服务/账号",
+	"secret.env_not_set":         "环境变量%s未设置",
+	"secret.keyring_unsupported": "暂不支持读取%s系统的钥匙串",
+	"secret.command_failed":      "执行 %s 失败：%s",
+	"cert.invalid":               "证书%s格式有误：%v",
+	"cert.not_user_cert":         "%s不是用户证书",
+	"cert.key_mismatch":          "证书%s与密钥不匹配",
+	"cert.not_yet_valid":         "%s尚未生效，生效时间为%s",
+	"cert.expired":               "%s已于%s过期，请重新签发证书",
+	"cert.invalid_ca":            "host_ca 配置%s有误，应为公钥或公钥文件路径",
+	"cert.host_name":             "主机%s的证书",
+	"debug.cert":                 "使用证书 %s，KeyId %s，principals %s",
+	"key.exists":                 "文件%s已存在",
+	"key.generated":              "已生成密钥 %s（%s）",
+	"key.no_targets":             "请指定目标服务器",
+	"key.no_old_key":             "服务器未使用密钥认证，请通过 -old 指定旧密钥",
+	"key.installed":              "已安装公钥并切换为密钥认证",
+	"key.verify_failed":          "使用新密钥登录失败，未修改配置：%v",
+	"key.failed":                 "%d/%d台服务器失败",
+	"escape.disconnected":        "已断开与 %s 的连接",
+	"escape.help":                "支持的转义序列：\n %[1]s.  断开连接\n %[1]sB  发送 BREAK\n %[1]sC  打开命令行（-L [bind:]port:host:hostport、-KL [bind:]port、cp [-r] source target）\n %[1]ss  显示连接信息（%[1]s# 同）\n %[1]s?  显示帮助\n %[1]s%[1]s  输入 %[1]s 本身\n（转义序列仅在行首生效）",
+	"escape.stats_server":        "服务器：%s（%v）",
+	"escape.stats_duration":      "连接时长：%v",
+	"escape.stats_bytes":         "已发送 %d 字节，已接收 %d 字节",
+	"escape.stats_forward":       "端口转发：%s -> %s",
+	"escape.invalid_command":     "不支持的命令：%s，可用命令为 -L、-KL、cp",
+	"escape.invalid_forward":     "转发配置%s有误，格式为 [bind:]port:host:hostport",
+	"escape.forward_exists":      "%s已在转发中",
+	"escape.forward_added":       "已添加端口转发 %s -> %s",
+	"escape.forward_not_found":   "未找到%s的端口转发",
+	"escape.forward_canceled":    "已取消端口转发 %s",
+	"zmodem.not_installed":       "未找到本地的 rz/sz 命令，请先安装 lrzsz",
+	"zmodem.prompt_upload":       "请输入要上传的文件，多个文件以空格分隔（为空时取消）：",
+	"zmodem.file_not_found":      "文件%s不存在",
+	"zmodem.progress_received":   "已接收 %s，%s/s",
+	"zmodem.progress_sent":       "已发送 %s，%s/s",
+	"zmodem.received":            "接收完成，文件已保存到%s",
+	"zmodem.sent":                "上传完成",
+	"zmodem.canceled":            "已取消传输",
+	"zmodem.failed":              "传输失败：%s",
+	"cp.invalid_limit":           "限速%s有误，格式如 512K、5M",
+	"cp.invalid_buffer":          "缓冲区大小%s有误，格式如 32K、1M",
+	"cp.summary":                 "共传输%d个文件，%s，平均速度 %s/s，耗时 %s，失败%d个",
+	"cp.invalid_pattern":         "匹配规则%s有误",
+	"server.ip_ambiguous":        "多台服务器的IP为%s，请使用编号或别名",
+	"cp.invalid_compress":        "压缩方式%s有误，可选 gzip、zstd",
+	"cp.tar_remote_to_remote":    "打包传输只支持本地与服务器之间复制",
+	"cp.tar_unsafe_path":         "包内路径%s指向目标目录之外，已停止解包",
+	"cp.zstd_not_found":          "无法执行本地的 zstd 命令：%v",
+	"cp.tar_progress":            "已传输 %s（实际传输 %s），%s/s，耗时 %s",
+	"debug.compression":          "当前使用的 x/crypto/ssh 不支持 SSH 层压缩，已忽略 Compression 选项，可使用 cp -tar -compress gzip",
+	"cp.tar_unsupported_type":    "不支持的文件类型 %q，已跳过",
+	"cp.tar_is_dir":              "%s已存在且为目录",
+	"cp.tar_bad_link":            "硬链接指向的%s不是已解包的普通文件",
+	"input.incomplete":           "请输入完整参数",
+	"input.index_required":       "请输入序号",
+	"flag.name":                  "名称",
+	"flag.ip":                    "IP或域名",
+	"flag.port":                  "端口",
+	"flag.user":                  "用户名",
+	"flag.method":                "认证方式：password/key",
+	"flag.key":                   "密钥路径",
+	"flag.alias":                 "别名",
+	"flag.add_group":             "组前缀或组名，为空时添加到默认组",
+	"flag.move_group":            "目标组前缀或组名，为空时不改变所在组",
+	"flag.ungroup":               "移动到默认组",
+	"flag.position":              "在组内的位置，从1开始，为0时移动到末尾",
+	"flag.group_name":            "组名",
+	"flag.prefix":                "前缀",
+	"flag.parent":                "上级组前缀，为空时创建顶级组",
+	"flag.new_name":              "新组名",
+	"flag.new_prefix":            "新前缀",
+	"flag.force":                 "同时删除组内服务器",
+	"flag.ungroup_servers":       "将组内服务器移动到默认组",
+	"flag.move_to":               "将组内服务器移动到指定组",
+	"server.edit_nothing":        "请指定要修改的内容",
+	"server.edited":              "已修改服务器：%s",
+	"server.removed":             "已删除服务器：%s",
+	"group.added":                "已添加组：%s",
+	"group.rename_nothing":       "请指定新组名或新前缀",
+	"group.renamed":              "已修改组：%s",
+	"group.removed":              "已删除组：%s",
+	"group.move_args":            "请输入组前缀及目标位置",
+	"group.moved":                "已移动组：%s",
+	"mux.start_failed":           "启动主连接失败：%s",
+	"mux.not_running":            "主连接未运行",
+	"flag.config":                "指定配置文件路径",
+	"flag.version":               "版本信息",
+	"flag.verbose":               "输出连接调试信息",
+	"flag.help":                  "帮助信息",
+	"version.author":             "由 Lenbo 编写，项目地址：https://github.com/islenbo/autossh。",
+	"config.index_duplicate":     "编号%s重复，请检查组前缀及别名",
+	"config.alias_duplicate":     "别名%s与其他服务器的编号或别名重复",
+	"config.alias_command":       "别名%s与子命令同名，无法在命令行中使用，请修改",
+	"config.id_negative":         "服务器%s的 id 不能为负数",
+	"config.id_duplicate":        "编号%s重复",
+	"config.backup":              "配置文件已备份：%s",
+	"flag.cp_dir":                "文件夹",
+	"cp.same_side":               "源和目标不能同时为本地地址",
+	"cp.is_dir":                  "是一个目录",
+	"flag.list_format":           "输出格式：table/json/csv",
+	"list.header":                "序号\t别名\t名称\t分组\t主机\t端口\t用户\t认证方式\t代理",
+	"list.invalid_format":        "不支持的输出格式：%s",
+	"export.type_required":       "请输入导出类型",
+	"export.invalid_type":        "不支持的导出类型：%s",
+	"export.proxy_auth":          "服务器%s的代理需要认证，nc 不支持代理认证，请改用 proxy_command",
+	"flag.export_format":         "输出格式：ini/yaml",
+	"flag.export_list":           "动态清单模式，输出全部主机",
+	"flag.export_host":           "动态清单模式，输出指定主机的变量",
+	"flag.status_json":           "以JSON格式输出",
+	"flag.status_load":           "登录并获取运行时间及负载",
+	"flag.status_timeout":        "超时秒数",
+	"status.invalid_timeout":     "超时时间必须大于0",
+	"status.header":              "序号\t名称\t地址\t状态\t延迟\t认证\t版本",
+	"status.header_load":         "\t运行时间\t负载",
+	"status.load_timeout":        "获取负载超时",
+	"status.no_banner":           "未读取到SSH版本信息",
+	"script.timeout":             "等待输出超时",
+	"flag.cluster_layout":        "显示方式：prefix/tmux",
+	"cluster.no_targets":         "请输入目标服务器",
+	"cluster.invalid_layout":     "不支持的显示方式：%s",
+	"cluster.tmux_required":      "tmux 显示方式需要在 tmux 会话中使用",
+	"cluster.tmux_window_failed": "创建 tmux 窗口失败：%v",
+	"cluster.tmux_pane_failed":   "创建 tmux 面板失败：%v",
+	"cluster.no_connections":     "没有可用的连接",
+	"cluster.connected":          "已连接 %d 台服务器，按 Ctrl-] 进入控制模式",
+	"cluster.disconnected":       "%s 连接已断开",
+	"cluster.prompt":             "cluster [序号]切换 [a]全部启用 [l]列表 [q]退出 > ",
+	"cluster.invalid_input":      "输入有误：%s",
+	"flag.password_source":       "密码来源，如 cmd:pass show web、env:WEB_PASSWORD、file:path、keyring:service/account，不支持在命令行中直接指定密码",
+	"flag.cert":                  "证书路径，为空时使用 密钥路径-cert.pub",
+	"flag.key_file":              "密钥路径，不存在时自动生成",
+	"flag.key_comment":           "公钥备注",
+	"flag.key_timeout":           "连接超时秒数",
+	"flag.key_old":               "要替换的旧密钥，为空时使用服务器当前的密钥",
+	"flag.cp_limit":              "限速，每秒字节数，如 512K、5M",
+	"flag.cp_buffer":             "读写缓冲区大小，如 32K、1M",
+	"flag.cp_include":            "复制目录时只传输匹配的文件，可重复指定",
+	"flag.cp_exclude":            "复制目录时跳过匹配的文件及目录，可重复指定",
+	"flag.cp_tar":                "打包传输目录，通过 exec 会话执行远程的 tar",
+	"flag.cp_compress":           "打包传输时的压缩方式 gzip|zstd",
+	"cert.host_untrusted":        "%s 未提供 host_ca 签发的证书，且主机密钥未通过 %s 校验：%v",
+	"debug.env_proxy_none":       "代理类型为 ENV，但未设置 ALL_PROXY/HTTPS_PROXY，直接连接",
+	"debug.env_no_proxy":         "%s 匹配 NO_PROXY，直接连接",
+	"debug.env_proxy":            "使用环境变量中的 %s 代理 %s",
+	"manage.unknown_operation":   "未知操作：%s",
+	"debug.try_password":         "尝试 password 认证",
+	"debug.try_publickey":        "尝试 publickey 认证：",
+	"debug.host_key":             "主机密钥：",
+	"debug.banner":               "服务器提示信息：",
+	"debug.connecting":           "正在连接 %s 连接超时 %v %s",
+	"debug.connected":            "已建立连接 %v 耗时 %v",
+	"debug.handshake":            "开始SSH握手，用户 %s 握手超时 %v",
+	"debug.authenticated":        "认证成功，服务器版本 %s 总耗时 %v",
+}
@@ -0,0 +1,86 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	En = "en"
+	Zh = "zh"
+
+	defaultLanguage = Zh
+)
+
+// 语言 => 消息key => 文本
+var catalogs = map[string]map[string]string{
+	En: en,
+	Zh: zh,
+}
+
+// 当前语言，默认按环境变量选择
+var language = detect()
+
+// 设置语言，支持 en、zh 及 zh_CN.UTF-8 等形式，不支持的语言返回 false
+func SetLanguage(lang string) bool {
+	if lang = normalize(lang); lang == "" {
+		return false
+	}
+
+	language = lang
+	return true
+}
+
+// 当前语言
+func Language() string {
+	return language
+}
+
+// 获取当前语言的文本，有参数时按 fmt.Sprintf 格式化
+// 当前语言缺少时使用默认语言，都缺少时返回 key
+func T(key string, args ...interface{}) string {
+	msg, ok := catalogs[language][key]
+	if !ok {
+		if msg, ok = catalogs[defaultLanguage][key]; !ok {
+			msg = key
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+
+	return msg
+}
+
+// 按 LC_ALL、LC_MESSAGES、LANG 的顺序选择语言
+func detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		if lang := normalize(value); lang != "" {
+			return lang
+		}
+		break
+	}
+
+	return defaultLanguage
+}
+
+// 将 zh_CN.UTF-8、en-US 等转换为语言代码，不支持时返回空
+func normalize(locale string) string {
+	lang := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(lang, "_-.@"); i != -1 {
+		lang = lang[:i]
+	}
+
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+
+	return ""
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var verbRegexp = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// 各语言的消息key及格式化参数需保持一致
+func TestCatalogKeys(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, msg := range catalogs[defaultLanguage] {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing key %q", lang, key)
+				continue
+			}
+			if translated == "" {
+				t.Errorf("%s: empty message %q", lang, key)
+			}
+			if len(verbRegexp.FindAllString(translated, -1)) != len(verbRegexp.FindAllString(msg, -1)) {
+				t.Errorf("%s: %q has different format verbs", lang, key)
+			}
+		}
+
+		for key := range catalog {
+			if _, ok := catalogs[defaultLanguage][key]; !ok {
+				t.Errorf("%s: unknown key %q", lang, key)
+			}
+		}
+	}
+}
+
+var keyRegexp = regexp.MustCompile(`i18n\.T\("([^"]+)"|Label: "([^"]+)"`)
+
+// 代码中使用的消息key必须存在于全部语言
+func TestSourceKeys(t *testing.T) {
+	files, err := filepath.Glob("../app/*.go")
+	if err != nil || len(files) == 0 {
+		t.Fatal("source files not found", err)
+	}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, matches := range keyRegexp.FindAllStringSubmatch(string(b), -1) {
+			key := matches[1] + matches[2]
+			for lang, catalog := range catalogs {
+				if _, ok := catalog[key]; !ok {
+					t.Errorf("%s: %s uses missing key %q", lang, filepath.Base(file), key)
+				}
+			}
+		}
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	defer SetLanguage(Language())
+
+	cases := map[string]string{"en_US.UTF-8": En, "zh_CN.UTF-8": Zh, "EN": En, "zh-TW": Zh}
+	for locale, want := range cases {
+		if !SetLanguage(locale) || Language() != want {
+			t.Errorf("SetLanguage(%q) = %s, want %s", locale, Language(), want)
+		}
+	}
+
+	SetLanguage(Zh)
+	if SetLanguage("fr_FR.UTF-8") || Language() != Zh {
+		t.Error("unsupported language should be ignored")
+	}
+
+	SetLanguage(En)
+	if T("group.not_found", "c") != "Group c does not exist" {
+		t.Errorf("T = %q", T("group.not_found", "c"))
+	}
+	if T("no.such.key") != "no.such.key" {
+		t.Error("missing key should return key")
+	}
+}
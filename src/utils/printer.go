@@ -1,6 +1,12 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
+
+// 是否输出调试信息
+var Verbose = false
 
 // 打印一行信息
 // 字体颜色为默色
@@ -38,6 +44,26 @@ func Errorln(a ...interface{}) {
 	fmt.Print("\033[0m")
 }
 
+// 打印一行警告到标准错误，不影响标准输出中的内容
+// 字体颜色为黄色
+func Warnln(a ...interface{}) {
+	fmt.Fprint(os.Stderr, "\033[33m")
+	fmt.Fprintln(os.Stderr, a...)
+	fmt.Fprint(os.Stderr, "\033[0m")
+}
+
+// 打印一行调试信息到标准错误，仅在开启调试时输出
+// 字体颜色为灰色
+func Debugln(a ...interface{}) {
+	if !Verbose {
+		return
+	}
+
+	fmt.Fprint(os.Stderr, "\033[90mdebug: ")
+	fmt.Fprintln(os.Stderr, a...)
+	fmt.Fprint(os.Stderr, "\033[0m")
+}
+
 // 二维数组对齐
 //func Align(arr [][]string) [][]string {
 //	for column := 0; column < 2; column++ {
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"errors"
 	"math"
 	"strconv"
+	"strings"
 )
 
 func SizeFormat(size float64) string {
@@ -12,6 +14,41 @@ func SizeFormat(size float64) string {
 		return "0 B"
 	}
 	i := math.Floor(math.Log(size) / math.Log(float64(k)))
+	if i < 0 {
+		i = 0
+	} else if i > float64(len(sizes)-1) {
+		i = float64(len(sizes) - 1)
+	}
 	r := size / math.Pow(float64(k), i)
 	return strconv.FormatFloat(r, 'f', 2, 64) + " " + sizes[int(i)]
 }
+
+// 解析容量字符串
+// 如：512 => 512，64K => 65536，5M => 5242880，1.5GB => 1610612736
+func ParseSize(str string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(str))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	if s == "" {
+		return 0, errors.New("invalid size: " + str)
+	}
+
+	units := map[byte]float64{
+		'K': 1 << 10,
+		'M': 1 << 20,
+		'G': 1 << 30,
+		'T': 1 << 40,
+	}
+
+	multiple := 1.0
+	if unit, ok := units[s[len(s)-1]]; ok {
+		multiple = unit
+		s = s[:len(s)-1]
+	}
+
+	num, err := strconv.ParseFloat(s, 64)
+	if err != nil || num < 0 {
+		return 0, errors.New("invalid size: " + str)
+	}
+
+	return int64(num * multiple), nil
+}
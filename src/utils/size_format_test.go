@@ -0,0 +1,33 @@
+package utils
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		str  string
+		size int64
+		err  bool
+	}{
+		{"512", 512, false},
+		{"0", 0, false},
+		{"10K", 10 << 10, false},
+		{"10kb", 10 << 10, false},
+		{"5M", 5 << 20, false},
+		{"5MiB", 5 << 20, false},
+		{"1.5G", 3 << 29, false},
+		{" 2T ", 2 << 40, false},
+		{"100B", 100, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"-1K", 0, true},
+		{"ten", 0, true},
+		{"5X", 0, true},
+	}
+
+	for _, c := range cases {
+		size, err := ParseSize(c.str)
+		if (err != nil) != c.err || size != c.size {
+			t.Errorf("ParseSize(%q) = %d %v, want %d", c.str, size, err, c.size)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"unicode"
 )
 
@@ -46,3 +47,8 @@ func FormatSeparator(title string, c string, maxlength int) string {
 //
 //	return body
 //}
+
+// 转义为shell单引号字符串，如 /tmp/a b => '/tmp/a b'
+func ShellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}